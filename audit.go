@@ -0,0 +1,66 @@
+// Copyright 2015 The pstree Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pstree
+
+import (
+	"fmt"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// AuditUnset is the value of ProcessStat.LoginUID and ProcessStat.SessionID
+// for processes not attached to a login session, such as daemons started at
+// boot time.
+// The kernel reports it as 4294967295, i.e. (uint32)-1.
+const AuditUnset = -1
+
+// WithAudit configures whether the audit login UID and session ID of each
+// process are read from /proc/[pid]/loginuid and /proc/[pid]/sessionid into
+// ProcessStat.LoginUID and ProcessStat.SessionID.
+// They are only available on kernels built with CONFIG_AUDIT; both are set
+// to AuditUnset otherwise, as well as when WithAudit is off and for the
+// threads of Process.Threads.
+func WithAudit(v bool) Option {
+	return func(cfg *config) {
+		cfg.audit = v
+	}
+}
+
+// readAudit reads the loginuid and sessionid files of the process whose
+// procfs directory is dir into stat.
+func readAudit(dir string, cfg config, stat *ProcessStat) error {
+	var err error
+	stat.LoginUID, err = readAuditID(path.Join(dir, "loginuid"), cfg)
+	if err != nil {
+		return err
+	}
+	stat.SessionID, err = readAuditID(path.Join(dir, "sessionid"), cfg)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func readAuditID(fname string, cfg config) (int, error) {
+	data, err := cfg.readFile(fname)
+	switch {
+	case err == nil:
+	case tolerate(err):
+		return AuditUnset, nil
+	default:
+		return AuditUnset, fmt.Errorf("could not read %s: %w", fname, err)
+	}
+
+	v, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 32)
+	if err != nil {
+		return AuditUnset, fmt.Errorf("%s: file format invalid: %w", fname, err)
+	}
+	if v == math.MaxUint32 {
+		return AuditUnset, nil
+	}
+	return int(v), nil
+}
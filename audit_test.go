@@ -0,0 +1,57 @@
+// Copyright 2015 The pstree Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pstree
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestAudit(t *testing.T) {
+	fsys := fstest.MapFS{
+		"1/stat":         {Data: []byte(statLine(1, "init", 0))},
+		"1/loginuid":     {Data: []byte("4294967295")},
+		"1/sessionid":    {Data: []byte("4294967295")},
+		"1000/stat":      {Data: []byte(statLine(1000, "bash", 1))},
+		"1000/loginuid":  {Data: []byte("0")},
+		"1000/sessionid": {Data: []byte("3")},
+	}
+
+	for _, tc := range []struct {
+		name  string
+		opts  []Option
+		login map[int]int
+		sess  map[int]int
+	}{
+		{
+			name:  "off",
+			login: map[int]int{1: AuditUnset, 1000: AuditUnset},
+			sess:  map[int]int{1: AuditUnset, 1000: AuditUnset},
+		},
+		{
+			name:  "on",
+			opts:  []Option{WithAudit(true)},
+			login: map[int]int{1: AuditUnset, 1000: 0},
+			sess:  map[int]int{1: AuditUnset, 1000: 3},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tree, err := NewFromFS(fsys, tc.opts...)
+			if err != nil {
+				t.Fatalf("could not create tree: %+v", err)
+			}
+			for pid, want := range tc.login {
+				if got := tree.Procs[pid].Stat.LoginUID; got != want {
+					t.Errorf("pid=%d: invalid loginuid: got=%d, want=%d", pid, got, want)
+				}
+			}
+			for pid, want := range tc.sess {
+				if got := tree.Procs[pid].Stat.SessionID; got != want {
+					t.Errorf("pid=%d: invalid sessionid: got=%d, want=%d", pid, got, want)
+				}
+			}
+		})
+	}
+}
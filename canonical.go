@@ -0,0 +1,50 @@
+// Copyright 2015 The pstree Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pstree
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Canonical returns a deterministic, human-diffable text representation of
+// the tree, suitable for committing snapshots to version control.
+//
+// Each process is displayed on its own line, under its parent, as box-drawn
+// text, with its name, PID, real UID and command line.
+// Volatile data such as CPU times, memory usage or the process state are
+// left out, and roots and children are always ordered by PID, regardless of
+// the order of Children.
+func (t *Tree) Canonical() string {
+	c := t.MapProcs(func(p Process) Process { return p })
+	c.SortChildren(ByPID)
+
+	r := TextRenderer{Label: canonicalLabel}
+	var o strings.Builder
+	for _, pid := range c.pids() {
+		if _, ok := c.Procs[c.Procs[pid].Stat.Ppid]; ok {
+			continue
+		}
+		_ = r.Render(&o, c, pid)
+	}
+	return o.String()
+}
+
+func canonicalLabel(p Process) string {
+	label := fmt.Sprintf("%s (pid %d, uid %d)", p.Name, p.Stat.PID, p.Stat.UIDs[0])
+	args, err := p.Stat.Args()
+	if err != nil {
+		return label
+	}
+	for _, arg := range args {
+		// quote arguments which would be ambiguous or span several lines.
+		if arg == "" || strings.ContainsAny(arg, " \t\n\"\\") || !strconv.CanBackquote(arg) {
+			arg = strconv.Quote(arg)
+		}
+		label += " " + arg
+	}
+	return label
+}
@@ -0,0 +1,118 @@
+// Copyright 2015 The pstree Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pstree
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// capNames holds the names of the Linux capabilities, indexed by their
+// number.
+// see: http://man7.org/linux/man-pages/man7/capabilities.7.html
+var capNames = [...]string{
+	"CAP_CHOWN",
+	"CAP_DAC_OVERRIDE",
+	"CAP_DAC_READ_SEARCH",
+	"CAP_FOWNER",
+	"CAP_FSETID",
+	"CAP_KILL",
+	"CAP_SETGID",
+	"CAP_SETUID",
+	"CAP_SETPCAP",
+	"CAP_LINUX_IMMUTABLE",
+	"CAP_NET_BIND_SERVICE",
+	"CAP_NET_BROADCAST",
+	"CAP_NET_ADMIN",
+	"CAP_NET_RAW",
+	"CAP_IPC_LOCK",
+	"CAP_IPC_OWNER",
+	"CAP_SYS_MODULE",
+	"CAP_SYS_RAWIO",
+	"CAP_SYS_CHROOT",
+	"CAP_SYS_PTRACE",
+	"CAP_SYS_PACCT",
+	"CAP_SYS_ADMIN",
+	"CAP_SYS_BOOT",
+	"CAP_SYS_NICE",
+	"CAP_SYS_RESOURCE",
+	"CAP_SYS_TIME",
+	"CAP_SYS_TTY_CONFIG",
+	"CAP_MKNOD",
+	"CAP_LEASE",
+	"CAP_AUDIT_WRITE",
+	"CAP_AUDIT_CONTROL",
+	"CAP_SETFCAP",
+	"CAP_MAC_OVERRIDE",
+	"CAP_MAC_ADMIN",
+	"CAP_SYSLOG",
+	"CAP_WAKE_ALARM",
+	"CAP_BLOCK_SUSPEND",
+	"CAP_AUDIT_READ",
+	"CAP_PERFMON",
+	"CAP_BPF",
+	"CAP_CHECKPOINT_RESTORE",
+}
+
+// WithCaps configures whether the inheritable, permitted and effective
+// capability sets of each process are read from /proc/[pid]/status into
+// ProcessStat.CapInh, ProcessStat.CapPrm and ProcessStat.CapEff.
+func WithCaps(v bool) Option {
+	return func(cfg *config) {
+		cfg.caps = v
+	}
+}
+
+// parseCaps parses the capability sets of a /proc/[pid]/status file into
+// stat.
+// Sets absent from data are left untouched.
+func parseCaps(stat *ProcessStat, data []byte) error {
+	for _, line := range strings.Split(string(data), "\n") {
+		key, val, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		var dst *uint64
+		switch key {
+		case "CapInh":
+			dst = &stat.CapInh
+		case "CapPrm":
+			dst = &stat.CapPrm
+		case "CapEff":
+			dst = &stat.CapEff
+		default:
+			continue
+		}
+		val = strings.TrimSpace(val)
+		v, err := strconv.ParseUint(val, 16, 64)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q: %w", key, val, err)
+		}
+		*dst = v
+	}
+	return nil
+}
+
+// CapNames returns the names of the capabilities in the effective set of the
+// process, e.g. "CAP_NET_ADMIN", ordered by capability number.
+// Capabilities unknown to this package are named after their number, e.g.
+// "CAP_41".
+// CapNames returns nil unless the process was scanned with WithCaps.
+func (p ProcessStat) CapNames() []string {
+	var names []string
+	for i := 0; i < 64; i++ {
+		if p.CapEff&(1<<uint(i)) == 0 {
+			continue
+		}
+		switch {
+		case i < len(capNames):
+			names = append(names, capNames[i])
+		default:
+			names = append(names, fmt.Sprintf("CAP_%d", i))
+		}
+	}
+	return names
+}
@@ -0,0 +1,97 @@
+// Copyright 2015 The pstree Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pstree
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Cgroup describes the control group of a process in one hierarchy, as
+// reported by /proc/[pid]/cgroup.
+type Cgroup struct {
+	ID          int      `json:"id"`                    // hierarchy ID, 0 for the cgroup v2 unified hierarchy
+	Controllers []string `json:"controllers,omitempty"` // controllers bound to the hierarchy, empty for cgroup v2
+	Path        string   `json:"path"`                  // path of the control group, relative to the mount point of the hierarchy
+}
+
+// WithCgroups configures whether the control groups of each process are read
+// from /proc/[pid]/cgroup into ProcessStat.Cgroups.
+func WithCgroups(v bool) Option {
+	return func(cfg *config) {
+		cfg.cgroups = v
+	}
+}
+
+// readCgroups reads the cgroup file of the process whose procfs directory is
+// dir.
+// It returns nil if the file is not available.
+func readCgroups(dir string, cfg config) ([]Cgroup, error) {
+	fname := path.Join(dir, "cgroup")
+	data, err := cfg.readFile(fname)
+	switch {
+	case err == nil:
+	case tolerate(err):
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("could not read %s: %w", fname, err)
+	}
+
+	var cgroups []Cgroup
+	for _, line := range strings.Split(string(data), "\n") {
+		if line == "" {
+			continue
+		}
+		toks := strings.SplitN(line, ":", 3)
+		if len(toks) != 3 {
+			return nil, fmt.Errorf("%s: invalid line %q", fname, line)
+		}
+		id, err := strconv.Atoi(toks[0])
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid hierarchy ID %q: %w", fname, toks[0], err)
+		}
+		cg := Cgroup{ID: id, Path: toks[2]}
+		if toks[1] != "" {
+			cg.Controllers = strings.Split(toks[1], ",")
+		}
+		cgroups = append(cgroups, cg)
+	}
+	return cgroups, nil
+}
+
+// SystemdUnit returns the name of the systemd unit owning the process, such
+// as "nginx.service" or "session-2.scope", as found in the path of its
+// control group: in the cgroup v2 unified hierarchy or, with cgroup v1, in
+// the "name=systemd" hierarchy.
+// The innermost unit is returned for nested units, such as services of
+// per-user managers.
+// SystemdUnit returns an empty string if the process was not scanned with
+// WithCgroups or does not belong to any systemd unit.
+func (p ProcessStat) SystemdUnit() string {
+	elems := strings.Split(p.systemdCgroup(), "/")
+	for i := len(elems) - 1; i >= 0; i-- {
+		elem := elems[i]
+		if strings.HasSuffix(elem, ".service") || strings.HasSuffix(elem, ".scope") {
+			return elem
+		}
+	}
+	return ""
+}
+
+// systemdCgroup returns the path of the control group of the process in the
+// hierarchy managed by systemd.
+func (p ProcessStat) systemdCgroup() string {
+	for _, cg := range p.Cgroups {
+		switch {
+		case cg.ID == 0 && len(cg.Controllers) == 0:
+			return cg.Path
+		case len(cg.Controllers) == 1 && cg.Controllers[0] == "name=systemd":
+			return cg.Path
+		}
+	}
+	return ""
+}
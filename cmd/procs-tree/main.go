@@ -8,8 +8,16 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io"
 	"log"
+	"os"
+	"os/signal"
+	"path"
+	"regexp"
+	"sort"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/sbinet/pstree"
 )
@@ -19,23 +27,265 @@ func main() {
 	log.SetFlags(0)
 
 	pid := flag.Int("pid", 1, "PID of the process tree to display")
+	cmdline := flag.Bool("cmdline", false, "display the full command line instead of the process name")
+	order := flag.String("sort", "pid", "order of children processes (pid, name, cpu, mem or start)")
+	kthreads := flag.Bool("k", true, "show kernel threads")
+	noKThreads := flag.Bool("no-kthreads", false, "hide kernel threads, including kthreadd, except the -pid process, displayed as a leaf (same as -k=false)")
+	markKThreads := flag.Bool("mark-kthreads", false, "display kernel threads with a [k] prefix instead of brackets")
+	totals := flag.Bool("totals", false, "annotate processes with the RSS and process count of their subtree")
+	collapse := flag.Bool("collapse", false, "display leaf sibling processes sharing the same name on a single line")
+	state := flag.Bool("state", false, "display the state of each process (R, S, D, Z, ...)")
+	columns := flag.Bool("columns", false, "display PIDs and RSS right-aligned in columns")
+	depth := flag.Int("depth", 0, "maximum depth of the displayed tree (0 for unlimited)")
+	watch := flag.Duration("watch", 0, "refresh the displayed tree every `DURATION`, until interrupted")
+	onceGone := flag.Bool("once-gone", false, "with -watch, exit when the displayed process is gone")
+	find := flag.String("find", "", "only display processes whose name or command line match `REGEX`, with their ancestors: all the matching trees are displayed, unless -pid, -watch or -tui is set")
+	procDir := flag.String("proc", "/proc", "`DIR` where procfs is mounted")
+	paths := flag.Bool("paths", false, "display the procfs directory of each process")
+	var excludes globs
+	flag.Var(&excludes, "exclude", "hide processes whose name matches `GLOB`, re-attaching their children to their parent (repeatable)")
+	interactive := flag.Bool("tui", false, "display an interactive tree (requires building with -tags tui)")
 
 	flag.Parse()
 
-	tree, err := pstree.New()
+	pidSet := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "pid" {
+			pidSet = true
+		}
+	})
+
+	less, ok := sorters[*order]
+	if !ok {
+		log.Printf("invalid -sort value %q (valid values: pid, name, cpu, mem, start)", *order)
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var match *regexp.Regexp
+	if *find != "" {
+		var err error
+		match, err = regexp.Compile(*find)
+		if err != nil {
+			log.Fatalf("invalid -find pattern: %+v", err)
+		}
+	}
+
+	load := func() (*pstree.Tree, error) {
+		tree, err := pstree.New(pstree.WithProcDir(*procDir))
+		if err != nil {
+			return nil, fmt.Errorf("could not create process tree: %w", err)
+		}
+
+		if !*kthreads || *noKThreads {
+			hideKThreads(tree, *pid)
+		}
+
+		if len(excludes) > 0 {
+			// excluded intermediate processes are removed and their children
+			// re-attached to their nearest displayed ancestor.
+			// The displayed root is always kept.
+			tree = tree.Prune(func(p pstree.Process) bool {
+				return p.Stat.PID != *pid && excludes.match(p.Name)
+			})
+		}
+
+		if *cmdline {
+			for i, proc := range tree.Procs {
+				proc.Name, err = cmdlineOf(proc)
+				if err != nil {
+					return nil, fmt.Errorf("could not decode command line: %w", err)
+				}
+				tree.Procs[i] = proc
+			}
+		}
+		tree.SortChildren(less)
+
+		if match != nil {
+			tree = tree.FilterTree(func(p pstree.Process) bool {
+				if match.MatchString(p.Name) {
+					return true
+				}
+				args, err := p.Stat.Args()
+				return err == nil && match.MatchString(strings.Join(args, " "))
+			})
+		}
+		return tree, nil
+	}
+
+	if *interactive {
+		err := runTUI(*pid, load)
+		if err != nil {
+			log.Fatalf("%+v", err)
+		}
+		return
+	}
+
+	r := pstree.TextRenderer{
+		Totals:   *totals,
+		Collapse: *collapse,
+		MaxDepth: *depth,
+
+		MarkKThreads: *markKThreads,
+		Columns:      *columns,
+		State:        *state,
+	}
+	if *paths {
+		r.ProcDir = *procDir
+	}
+
+	if *watch > 0 {
+		err := watchTree(os.Stdout, *pid, *watch, *onceGone, load, &r)
+		if err != nil {
+			log.Fatalf("%+v", err)
+		}
+		return
+	}
+
+	tree, err := load()
 	if err != nil {
-		log.Fatalf("could not create process tree: %+v", err)
+		log.Fatalf("%+v", err)
 	}
 
-	fmt.Printf("tree[%d]: %v\n", *pid, tree.Procs[*pid])
-	display(*pid, tree, 1)
+	roots := []int{*pid}
+	switch {
+	case match != nil && !pidSet:
+		// the minimal forest holding all the matching processes.
+		roots = rootsOf(tree)
+		if len(roots) == 0 {
+			log.Fatalf("no process matching %q", *find)
+		}
+	case match != nil:
+		if _, ok := tree.Procs[*pid]; !ok {
+			log.Fatalf("no process matching %q under pid=%d", *find, *pid)
+		}
+	}
+
+	for _, root := range roots {
+		err = r.Render(os.Stdout, tree, root)
+		if err != nil {
+			log.Fatalf("could not display process tree: %+v", err)
+		}
+	}
+}
+
+// rootsOf returns the PIDs of the processes of the tree whose parent is not
+// part of the tree, sorted.
+func rootsOf(tree *pstree.Tree) []int {
+	var roots []int
+	for pid, proc := range tree.Procs {
+		if _, ok := tree.Procs[proc.Stat.Ppid]; !ok {
+			roots = append(roots, pid)
+		}
+	}
+	sort.Ints(roots)
+	return roots
+}
+
+// watchTree clears the terminal and displays the tree rooted at root every
+// period, until interrupted.
+// If the root process is gone, watchTree keeps waiting for it, unless
+// exitIfGone is set.
+func watchTree(w io.Writer, root int, period time.Duration, exitIfGone bool, load func() (*pstree.Tree, error), r *pstree.TextRenderer) error {
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigc)
+
+	tick := time.NewTicker(period)
+	defer tick.Stop()
+
+	for {
+		tree, err := load()
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprint(w, "\033[H\033[2J") // clear screen.
+		switch _, ok := tree.Procs[root]; {
+		case ok:
+			err = r.Render(w, tree, root)
+			if err != nil {
+				return fmt.Errorf("could not display process tree: %w", err)
+			}
+		default:
+			fmt.Fprintf(w, "process %d is gone\n", root)
+			if exitIfGone {
+				return nil
+			}
+		}
+
+		select {
+		case <-sigc:
+			return nil
+		case <-tick.C:
+		}
+	}
+}
+
+// hideKThreads removes all kernel threads from the tree, except root.
+// kthreadd is a kernel thread too and is removed along with its children:
+// if root is a kernel thread, such as kthreadd, it is kept without its
+// children, so it is displayed as a leaf.
+func hideKThreads(tree *pstree.Tree, root int) {
+	for pid, proc := range tree.Procs {
+		if proc.Stat.IsKernelThread() && pid != root {
+			delete(tree.Procs, pid)
+		}
+	}
+	for pid, proc := range tree.Procs {
+		children := proc.Children[:0:0]
+		for _, cid := range proc.Children {
+			if _, ok := tree.Procs[cid]; ok {
+				children = append(children, cid)
+			}
+		}
+		proc.Children = children
+		tree.Procs[pid] = proc
+	}
+}
+
+var sorters = map[string]func(a, b pstree.Process) bool{
+	"pid":   pstree.ByPID,
+	"name":  pstree.ByName,
+	"cpu":   pstree.ByCPU,
+	"mem":   pstree.ByMem,
+	"start": pstree.ByStart,
+}
+
+// cmdlineOf returns the space-joined command line of the process.
+// Processes without a command line (e.g. kernel threads) are displayed
+// with their bracketed name, as ps(1) does.
+func cmdlineOf(proc pstree.Process) (string, error) {
+	args, err := proc.Stat.Args()
+	if err != nil {
+		return "", err
+	}
+	if len(args) == 0 {
+		return "[" + proc.Stat.Comm + "]", nil
+	}
+	return strings.Join(args, " "), nil
+}
+
+// globs is a repeatable flag of glob patterns, as understood by path.Match.
+type globs []string
+
+func (g *globs) String() string { return strings.Join(*g, ",") }
+
+func (g *globs) Set(v string) error {
+	_, err := path.Match(v, "")
+	if err != nil {
+		return fmt.Errorf("invalid glob %q: %w", v, err)
+	}
+	*g = append(*g, v)
+	return nil
 }
 
-func display(pid int, tree *pstree.Tree, indent int) {
-	str := strings.Repeat("  ", indent)
-	for _, cid := range tree.Procs[pid].Children {
-		proc := tree.Procs[cid]
-		fmt.Printf("%s%#v\n", str, proc)
-		display(cid, tree, indent+1)
+// match reports whether name matches any of the patterns.
+func (g globs) match(name string) bool {
+	for _, pattern := range g {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
 	}
+	return false
 }
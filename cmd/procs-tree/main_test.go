@@ -0,0 +1,173 @@
+// Copyright 2015 The pstree Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	if os.Getenv("PROCS_TREE_MAIN") == "1" {
+		// run as procs-tree, for the tests of its command line.
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// procsTree runs procs-tree with args and returns its combined output and
+// exit code.
+func procsTree(t *testing.T, args ...string) (string, int) {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Env = append(os.Environ(), "PROCS_TREE_MAIN=1")
+	out, err := cmd.CombinedOutput()
+	switch err := err.(type) {
+	case nil:
+		return string(out), 0
+	case *exec.ExitError:
+		return string(out), err.ExitCode()
+	default:
+		t.Fatalf("could not run procs-tree: %+v", err)
+		return "", 0
+	}
+}
+
+// fakeProcDir creates a procfs directory with two roots: init (pid 1) and
+// kthreadd (pid 2), with its kernel threads.
+func fakeProcDir(t *testing.T) string {
+	t.Helper()
+	const pfKthread = 0x00200000
+	dir := t.TempDir()
+	for _, p := range []struct {
+		pid   int
+		comm  string
+		ppid  int
+		flags int
+	}{
+		{1, "init", 0, 0},
+		{2, "kthreadd", 0, pfKthread},
+		{3, "kworker/0:0", 2, pfKthread},
+		{100, "sshd", 1, 0},
+		{101, "bash", 100, 0},
+		{200, "cron", 1, 0},
+	} {
+		stat := fmt.Sprintf(
+			"%d (%s) S %d %d %d 0 -1 %d 10 0 2 0 3 4 0 0 20 0 1 0 %d 8192 100 18446744073709551615 0 0 0 0 0 0 0 0 0 0 0 0 17 2 0 0 0 0 0\n",
+			p.pid, p.comm, p.ppid, p.pid, p.pid, p.flags, 100+p.pid,
+		)
+		pdir := filepath.Join(dir, fmt.Sprint(p.pid))
+		err := os.Mkdir(pdir, 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.WriteFile(filepath.Join(pdir, "stat"), []byte(stat), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestFind(t *testing.T) {
+	dir := fakeProcDir(t)
+	for _, tc := range []struct {
+		name string
+		args []string
+		want string
+		code int
+	}{
+		{
+			name: "other-root",
+			args: []string{"-find", "kworker"},
+			want: `kthreadd (pid 2)
+└─ kworker/0:0 (pid 3)
+`,
+		},
+		{
+			name: "all-roots",
+			args: []string{"-find", "bash|kworker"},
+			want: `init (pid 1)
+└─ sshd (pid 100)
+   └─ bash (pid 101)
+kthreadd (pid 2)
+└─ kworker/0:0 (pid 3)
+`,
+		},
+		{
+			name: "pid",
+			args: []string{"-find", "bash|kworker", "-pid", "1"},
+			want: `init (pid 1)
+└─ sshd (pid 100)
+   └─ bash (pid 101)
+`,
+		},
+		{
+			name: "no-match",
+			args: []string{"-find", "nginx"},
+			want: "procs-tree: no process matching \"nginx\"\n",
+			code: 1,
+		},
+		{
+			name: "no-match-under-pid",
+			args: []string{"-find", "kworker", "-pid", "1"},
+			want: "procs-tree: no process matching \"kworker\" under pid=1\n",
+			code: 1,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			out, code := procsTree(t, append([]string{"-proc", dir}, tc.args...)...)
+			if code != tc.code {
+				t.Fatalf("invalid exit code: got=%d, want=%d\n%s", code, tc.code, out)
+			}
+			if out != tc.want {
+				t.Fatalf("invalid output:\ngot:\n%s\nwant:\n%s", out, tc.want)
+			}
+		})
+	}
+}
+
+func TestNoKThreads(t *testing.T) {
+	dir := fakeProcDir(t)
+	for _, tc := range []struct {
+		name string
+		args []string
+		want string
+	}{
+		{
+			name: "init",
+			args: []string{"-no-kthreads"},
+			want: `init (pid 1)
+├─ sshd (pid 100)
+│  └─ bash (pid 101)
+└─ cron (pid 200)
+`,
+		},
+		{
+			name: "kthreadd",
+			args: []string{"-no-kthreads", "-pid", "2"},
+			want: "kthreadd (pid 2)\n",
+		},
+		{
+			name: "kworker",
+			args: []string{"-k=false", "-pid", "3"},
+			want: "kworker/0:0 (pid 3)\n",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			out, code := procsTree(t, append([]string{"-proc", dir}, tc.args...)...)
+			if code != 0 {
+				t.Fatalf("invalid exit code: got=%d, want=0\n%s", code, out)
+			}
+			if out != tc.want {
+				t.Fatalf("invalid output:\ngot:\n%s\nwant:\n%s", out, tc.want)
+			}
+		})
+	}
+}
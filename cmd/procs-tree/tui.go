@@ -0,0 +1,234 @@
+// Copyright 2015 The pstree Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build tui
+// +build tui
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+	"github.com/sbinet/pstree"
+)
+
+// refresh is the period at which the interactive tree is reloaded.
+const refresh = 2 * time.Second
+
+// runTUI displays an interactive view of the process tree rooted at root.
+// Subtrees can be folded and unfolded, and the tree is reloaded with load
+// every refresh period.
+func runTUI(root int, load func() (*pstree.Tree, error)) error {
+	tree, err := load()
+	if err != nil {
+		return err
+	}
+
+	scr, err := tcell.NewScreen()
+	if err != nil {
+		return fmt.Errorf("could not create terminal screen: %w", err)
+	}
+	err = scr.Init()
+	if err != nil {
+		return fmt.Errorf("could not initialize terminal screen: %w", err)
+	}
+	defer scr.Fini()
+
+	quit := make(chan struct{})
+	defer close(quit)
+	go func() {
+		tick := time.NewTicker(refresh)
+		defer tick.Stop()
+		for {
+			select {
+			case <-quit:
+				return
+			case <-tick.C:
+				tree, err := load()
+				if err != nil {
+					// keep displaying the previous tree.
+					continue
+				}
+				_ = scr.PostEvent(tcell.NewEventInterrupt(tree))
+			}
+		}
+	}()
+
+	ui := &tui{
+		scr:    scr,
+		root:   root,
+		tree:   tree,
+		sel:    root,
+		folded: make(map[int]bool),
+	}
+	for {
+		ui.draw()
+		switch ev := scr.PollEvent().(type) {
+		case *tcell.EventResize:
+			scr.Sync()
+		case *tcell.EventInterrupt:
+			ui.tree = ev.Data().(*pstree.Tree)
+		case *tcell.EventKey:
+			switch ev.Key() {
+			case tcell.KeyEscape, tcell.KeyCtrlC:
+				return nil
+			case tcell.KeyUp:
+				ui.move(-1)
+			case tcell.KeyDown:
+				ui.move(+1)
+			case tcell.KeyPgUp:
+				ui.move(-ui.height())
+			case tcell.KeyPgDn:
+				ui.move(+ui.height())
+			case tcell.KeyHome:
+				ui.move(-len(ui.rows))
+			case tcell.KeyEnd:
+				ui.move(+len(ui.rows))
+			case tcell.KeyLeft:
+				ui.folded[ui.sel] = true
+			case tcell.KeyRight:
+				delete(ui.folded, ui.sel)
+			case tcell.KeyEnter:
+				ui.toggle()
+			case tcell.KeyRune:
+				switch ev.Rune() {
+				case 'q':
+					return nil
+				case ' ':
+					ui.toggle()
+				}
+			}
+		}
+	}
+}
+
+// row is a displayed line of the interactive tree.
+type row struct {
+	proc  pstree.Process
+	depth int
+}
+
+type tui struct {
+	scr    tcell.Screen
+	root   int
+	tree   *pstree.Tree
+	rows   []row
+	sel    int          // PID of the selected process
+	top    int          // index of the first displayed row
+	folded map[int]bool // PIDs of the folded processes
+}
+
+// height returns the number of rows available to display the tree.
+func (ui *tui) height() int {
+	_, h := ui.scr.Size()
+	if h < 2 {
+		return 1
+	}
+	return h - 1 // last line is the help line.
+}
+
+// layout computes the visible rows of the tree, skipping the descendants of
+// folded processes.
+func (ui *tui) layout() {
+	ui.rows = ui.rows[:0]
+	_ = ui.tree.Walk(ui.root, func(p pstree.Process, depth int) error {
+		ui.rows = append(ui.rows, row{proc: p, depth: depth})
+		if ui.folded[p.Stat.PID] {
+			return pstree.SkipChildren
+		}
+		return nil
+	})
+}
+
+// cursor returns the index of the selected row.
+// If the selected process vanished, the root is selected.
+func (ui *tui) cursor() int {
+	for i, r := range ui.rows {
+		if r.proc.Stat.PID == ui.sel {
+			return i
+		}
+	}
+	ui.sel = ui.root
+	return 0
+}
+
+func (ui *tui) move(n int) {
+	if len(ui.rows) == 0 {
+		return
+	}
+	i := ui.cursor() + n
+	switch {
+	case i < 0:
+		i = 0
+	case i >= len(ui.rows):
+		i = len(ui.rows) - 1
+	}
+	ui.sel = ui.rows[i].proc.Stat.PID
+}
+
+func (ui *tui) toggle() {
+	if ui.folded[ui.sel] {
+		delete(ui.folded, ui.sel)
+		return
+	}
+	ui.folded[ui.sel] = true
+}
+
+func (ui *tui) draw() {
+	ui.scr.Clear()
+	ui.layout()
+
+	var (
+		w, _ = ui.scr.Size()
+		h    = ui.height()
+	)
+	if len(ui.rows) == 0 {
+		ui.putStr(0, 0, fmt.Sprintf("process %d is gone", ui.root), tcell.StyleDefault)
+	}
+
+	cur := ui.cursor()
+	switch {
+	case cur < ui.top:
+		ui.top = cur
+	case cur >= ui.top+h:
+		ui.top = cur - h + 1
+	}
+
+	for y := 0; y < h && ui.top+y < len(ui.rows); y++ {
+		r := ui.rows[ui.top+y]
+		mark := "  "
+		switch {
+		case len(r.proc.Children) == 0:
+		case ui.folded[r.proc.Stat.PID]:
+			mark = "+ "
+		default:
+			mark = "- "
+		}
+		line := fmt.Sprintf("%s%s%s (%d)", strings.Repeat("  ", r.depth), mark, r.proc.Name, r.proc.Stat.PID)
+		style := tcell.StyleDefault
+		if ui.top+y == cur {
+			style = style.Reverse(true)
+			if n := w - len(line); n > 0 {
+				line += strings.Repeat(" ", n)
+			}
+		}
+		ui.putStr(0, y, line, style)
+	}
+
+	help := "↑/↓: move  ←/→: fold/unfold  space: toggle  q: quit"
+	ui.putStr(0, h, help, tcell.StyleDefault.Dim(true))
+	ui.scr.Show()
+}
+
+// putStr draws str at column x of row y.
+func (ui *tui) putStr(x, y int, str string, style tcell.Style) {
+	for _, r := range str {
+		ui.scr.SetContent(x, y, r, nil, style)
+		x += runewidth.RuneWidth(r)
+	}
+}
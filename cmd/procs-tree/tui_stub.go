@@ -0,0 +1,18 @@
+// Copyright 2015 The pstree Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !tui
+// +build !tui
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/sbinet/pstree"
+)
+
+func runTUI(root int, load func() (*pstree.Tree, error)) error {
+	return fmt.Errorf("interactive mode not available: rebuild procs-tree with -tags tui")
+}
@@ -0,0 +1,81 @@
+// Copyright 2015 The pstree Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pstree
+
+import (
+	"encoding/binary"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// CPURate returns the fraction of a CPU core used by the process pid between
+// the old and new snapshots of the process tree, taken dt apart.
+// A process fully using 2 cores has a rate of 2.
+//
+// CPURate returns 0 when pid is not present in both snapshots, or when the
+// PID has been reused by another process in-between.
+func CPURate(old, new *Tree, pid int, dt time.Duration) float64 {
+	p0, ok := old.Procs[pid]
+	if !ok {
+		return 0
+	}
+	p1, ok := new.Procs[pid]
+	if !ok {
+		return 0
+	}
+	if p0.Stat.Starttime != p1.Stat.Starttime {
+		// PID was reused.
+		return 0
+	}
+
+	var (
+		t0 = cpuTime(p0)
+		t1 = cpuTime(p1)
+	)
+	if dt <= 0 || t1 < t0 {
+		return 0
+	}
+	return float64(t1-t0) / (dt.Seconds() * float64(clockTicks()))
+}
+
+var clktck struct {
+	once sync.Once
+	hz   int64
+}
+
+// clockTicks returns the number of clock ticks per second (USER_HZ), the unit
+// of the time fields in /proc/[pid]/stat.
+// It is read from the auxiliary vector of the current process, falling back
+// to the ubiquitous value of 100.
+func clockTicks() int64 {
+	clktck.once.Do(func() {
+		clktck.hz = 100
+		auxv, err := os.ReadFile("/proc/self/auxv")
+		if err != nil {
+			return
+		}
+		const atClktck = 17 // AT_CLKTCK, from <elf.h>
+		size := strconv.IntSize / 8
+		for i := 0; i+2*size <= len(auxv); i += 2 * size {
+			key := auxvWord(auxv[i:], size)
+			if key == atClktck {
+				if hz := auxvWord(auxv[i+size:], size); hz > 0 {
+					clktck.hz = int64(hz)
+				}
+				return
+			}
+		}
+	})
+	return clktck.hz
+}
+
+func auxvWord(p []byte, size int) uint64 {
+	if size == 4 {
+		return uint64(binary.NativeEndian.Uint32(p))
+	}
+	return binary.NativeEndian.Uint64(p)
+}
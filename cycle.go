@@ -0,0 +1,114 @@
+// Copyright 2015 The pstree Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pstree
+
+import (
+	"fmt"
+	"sort"
+)
+
+// CyclePolicy defines how cycles of parent processes, as found in malformed
+// snapshots, are handled when creating a tree.
+// A process whose parent is itself forms a cycle of its own.
+type CyclePolicy int
+
+const (
+	// CycleBreak breaks each cycle by detaching its process with the lowest
+	// PID from its parent: that process becomes a root of the tree, with a
+	// zero Ppid.
+	CycleBreak CyclePolicy = iota
+
+	// CycleError makes the creation of the tree fail.
+	CycleError
+)
+
+// WithCyclePolicy sets how cycles of parent processes are handled.
+// The default is CycleBreak.
+// Either way, detected cycles are reported in Tree.Cycles.
+func WithCyclePolicy(p CyclePolicy) Option {
+	return func(cfg *config) {
+		cfg.cycles = p
+	}
+}
+
+// findCycles returns the cycles of parent processes in procs.
+// Each cycle is listed from its lowest PID, following parents, and cycles
+// are sorted by their first PID.
+func findCycles(procs map[int]Process) [][]int {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+
+	var (
+		cycles [][]int
+		state  = make(map[int]int, len(procs))
+	)
+	for pid := range procs {
+		var chain []int
+		for cur := pid; ; {
+			proc, ok := procs[cur]
+			if !ok || state[cur] == visited {
+				break
+			}
+			if state[cur] == visiting {
+				for i, p := range chain {
+					if p == cur {
+						cycles = append(cycles, rotateMin(chain[i:]))
+						break
+					}
+				}
+				break
+			}
+			state[cur] = visiting
+			chain = append(chain, cur)
+			cur = proc.Stat.Ppid
+		}
+		for _, p := range chain {
+			state[p] = visited
+		}
+	}
+
+	sort.Slice(cycles, func(i, j int) bool {
+		return cycles[i][0] < cycles[j][0]
+	})
+	return cycles
+}
+
+// rotateMin returns a copy of cycle, rotated to start at its lowest PID.
+func rotateMin(cycle []int) []int {
+	imin := 0
+	for i, pid := range cycle {
+		if pid < cycle[imin] {
+			imin = i
+		}
+	}
+	return append(append([]int(nil), cycle[imin:]...), cycle[:imin]...)
+}
+
+// breakCycles detaches the first process of each cycle from its parent.
+func breakCycles(procs map[int]Process, cycles [][]int) {
+	for _, cycle := range cycles {
+		proc := procs[cycle[0]]
+		parent := procs[proc.Stat.Ppid]
+		children := parent.Children[:0:0]
+		for _, cid := range parent.Children {
+			if cid != proc.Stat.PID {
+				children = append(children, cid)
+			}
+		}
+		parent.Children = children
+		procs[parent.Stat.PID] = parent
+
+		proc = procs[cycle[0]] // parent may be proc itself.
+		proc.Stat.Ppid = 0
+		procs[proc.Stat.PID] = proc
+	}
+}
+
+// cycleError returns the error reporting the cycles of a tree.
+func cycleError(cycles [][]int) error {
+	return fmt.Errorf("pstree: %d cycle(s) of parent processes, first one: %v", len(cycles), cycles[0])
+}
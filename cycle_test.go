@@ -0,0 +1,118 @@
+// Copyright 2015 The pstree Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pstree
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+	"testing/fstest"
+)
+
+// cyclicFS returns a procfs snapshot holding two cycles of parent
+// processes: 10 -> 20 -> 30 -> 10, with 40 hanging from 20, and 60 -> 70
+// -> 60.
+func cyclicFS() fstest.MapFS {
+	fsys := make(fstest.MapFS)
+	for _, p := range []struct{ pid, ppid int }{
+		{1, 0}, {2, 1},
+		{10, 20}, {20, 30}, {30, 10}, {40, 20},
+		{60, 70}, {70, 60},
+	} {
+		fsys[strconv.Itoa(p.pid)+"/stat"] = &fstest.MapFile{
+			Data: []byte(statLine(p.pid, "proc", p.ppid)),
+		}
+	}
+	return fsys
+}
+
+func TestCycleBreak(t *testing.T) {
+	tree, err := NewFromFS(cyclicFS(), WithCyclePolicy(CycleBreak))
+	if err != nil {
+		t.Fatalf("could not create tree: %+v", err)
+	}
+
+	if got, want := tree.Cycles, [][]int{{10, 20, 30}, {60, 70}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("invalid cycles: got=%v, want=%v", got, want)
+	}
+
+	for _, pid := range []int{10, 60} {
+		if ppid := tree.Procs[pid].Stat.Ppid; ppid != 0 {
+			t.Fatalf("cycle not broken at pid=%d: ppid=%d", pid, ppid)
+		}
+	}
+
+	for _, tc := range []struct {
+		root int
+		want []int
+	}{
+		{root: 10, want: []int{10, 20, 30, 40}},
+		{root: 60, want: []int{60, 70}},
+	} {
+		var got []int
+		err := tree.Walk(tc.root, func(p Process, depth int) error {
+			got = append(got, p.Stat.PID)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("could not walk tree: %+v", err)
+		}
+		sort.Ints(got)
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Fatalf("invalid subtree of pid=%d: got=%v, want=%v", tc.root, got, tc.want)
+		}
+	}
+
+	_, err = tree.TopoSort()
+	if err != nil {
+		t.Fatalf("broken tree still has cycles: %+v", err)
+	}
+}
+
+func TestCycleError(t *testing.T) {
+	tree, err := NewFromFS(cyclicFS(), WithCyclePolicy(CycleError))
+	if err == nil {
+		t.Fatalf("expected an error")
+	}
+	if tree != nil {
+		t.Fatalf("expected a nil tree")
+	}
+	if got, want := err.Error(), "pstree: 2 cycle(s) of parent processes, first one: [10 20 30]"; got != want {
+		t.Fatalf("invalid error:\ngot= %q\nwant=%q", got, want)
+	}
+}
+
+func TestSelfParent(t *testing.T) {
+	fsys := fstest.MapFS{
+		"1/stat": {Data: []byte(statLine(1, "init", 0))},
+		"5/stat": {Data: []byte(statLine(5, "self", 5))},
+		"6/stat": {Data: []byte(statLine(6, "child", 5))},
+	}
+
+	tree, err := NewFromFS(fsys)
+	if err != nil {
+		t.Fatalf("could not create tree: %+v", err)
+	}
+
+	self := tree.Procs[5]
+	if got, want := self.Children, []int{6}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("invalid children: got=%v, want=%v", got, want)
+	}
+	if self.Stat.Ppid != 0 {
+		t.Fatalf("self-parented process not made a root: ppid=%d", self.Stat.Ppid)
+	}
+	if got, want := tree.Cycles, [][]int{{5}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("invalid cycles: got=%v, want=%v", got, want)
+	}
+	if n := tree.CountDescendants(5); n != 1 {
+		t.Fatalf("invalid number of descendants: got=%d, want=1", n)
+	}
+
+	_, err = NewFromFS(fsys, WithCyclePolicy(CycleError))
+	if err == nil {
+		t.Fatalf("expected an error")
+	}
+}
@@ -0,0 +1,174 @@
+// Copyright 2015 The pstree Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pstree
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Args returns the decoded command line of the process, one element per
+// argument.
+// Args returns an empty slice for processes without a command line, such as
+// kernel threads or zombies.
+func (p ProcessStat) Args() ([]string, error) {
+	raw, err := blob(p.CmdlineRaw, p.Cmdline)
+	if err != nil {
+		return nil, fmt.Errorf("pstree: could not decode cmdline of pid=%d: %w", p.PID, err)
+	}
+	return splitNUL(raw), nil
+}
+
+// blob returns raw if it is set (see WithRawBlobs), and the decoded
+// base64 string enc otherwise.
+func blob(raw []byte, enc string) ([]byte, error) {
+	if raw != nil {
+		return raw, nil
+	}
+	return base64.StdEncoding.DecodeString(enc)
+}
+
+// ArgsLen returns the size, in bytes, of the command line of the process,
+// NUL separators included, as read from /proc/[pid]/cmdline.
+// It does not need to decode the command line.
+func (p ProcessStat) ArgsLen() int {
+	return blobLen(p.CmdlineRaw, p.Cmdline)
+}
+
+// EnvSize returns the size, in bytes, of the environment of the process,
+// NUL separators included, as read from /proc/[pid]/environ.
+// It does not need to decode the environment.
+func (p ProcessStat) EnvSize() int {
+	return blobLen(p.EnvironRaw, p.Environ)
+}
+
+// blobLen returns the length of raw if it is set (see WithRawBlobs), and
+// the decoded length of the base64 string enc otherwise.
+func blobLen(raw []byte, enc string) int {
+	if raw != nil {
+		return len(raw)
+	}
+	n := base64.StdEncoding.DecodedLen(len(enc))
+	return n - (len(enc) - len(strings.TrimRight(enc, "=")))
+}
+
+// splitNUL splits a NUL-separated (and possibly NUL-terminated) blob, as
+// found in /proc/[pid]/cmdline and /proc/[pid]/environ.
+func splitNUL(raw []byte) []string {
+	raw = bytes.TrimRight(raw, "\x00")
+	if len(raw) == 0 {
+		return nil
+	}
+	toks := bytes.Split(raw, []byte{0})
+	out := make([]string, len(toks))
+	for i, tok := range toks {
+		out[i] = string(tok)
+	}
+	return out
+}
+
+// argv0 returns the base name of the first element of the command line of
+// the process, with surrounding spaces trimmed, or an empty string.
+func argv0(p ProcessStat) string {
+	args, err := p.Args()
+	if err != nil || len(args) == 0 {
+		return ""
+	}
+	name := strings.TrimSpace(args[0])
+	if name == "" {
+		return ""
+	}
+	return filepath.Base(name)
+}
+
+// commLen is the maximum length of a process name as reported by the kernel
+// (TASK_COMM_LEN, minus the terminating NUL).
+const commLen = 15
+
+// CommMismatch reports whether the name of the process, as reported by the
+// kernel, differs from the basename of its argv[0], truncated to 15
+// characters like the kernel does.
+// Such a mismatch is a classic signal of a process masquerading as another
+// one, but it is also legitimately shown by processes rewriting their argv
+// (e.g. sshd or postgres) and by scripts run through an interpreter.
+//
+// Processes without a command line, such as kernel threads, never mismatch.
+func (p Process) CommMismatch() (bool, error) {
+	args, err := p.Stat.Args()
+	if err != nil {
+		return false, err
+	}
+	if len(args) == 0 {
+		return false, nil
+	}
+	name := filepath.Base(args[0])
+	if len(name) > commLen {
+		name = name[:commLen]
+	}
+	return name != p.Stat.Comm, nil
+}
+
+// FullName returns the name of the process, recovered from the base name of
+// its argv[0] when the kernel truncated it to 15 characters, e.g.
+// "systemd-journald" instead of "systemd-journal".
+// Otherwise, including for processes without a command line such as kernel
+// threads, FullName returns the kernel name of the process.
+func (p Process) FullName() string {
+	comm := p.Stat.Comm
+	if len(comm) < commLen {
+		return comm
+	}
+	name := argv0(p.Stat)
+	if len(name) > len(comm) && strings.HasPrefix(name, comm) {
+		return name
+	}
+	return comm
+}
+
+// Env returns the decoded environment of the process, keyed by variable
+// name.
+// Entries without a "=" separator are kept, with an empty value.
+func (p ProcessStat) Env() (map[string]string, error) {
+	raw, err := blob(p.EnvironRaw, p.Environ)
+	if err != nil {
+		return nil, fmt.Errorf("pstree: could not decode environ of pid=%d: %w", p.PID, err)
+	}
+	kvs := splitNUL(raw)
+	env := make(map[string]string, len(kvs))
+	for _, kv := range kvs {
+		k, v, _ := strings.Cut(kv, "=")
+		env[k] = v
+	}
+	return env, nil
+}
+
+// EnvValue returns the value of the environment variable key of the process,
+// and whether it was found.
+// It scans the environment blob without decoding it into a map.
+func (p ProcessStat) EnvValue(key string) (string, bool) {
+	raw, err := blob(p.EnvironRaw, p.Environ)
+	if err != nil {
+		return "", false
+	}
+
+	prefix := []byte(key + "=")
+	for len(raw) > 0 {
+		kv := raw
+		i := bytes.IndexByte(raw, 0)
+		switch {
+		case i < 0:
+			raw = nil
+		default:
+			kv, raw = raw[:i], raw[i+1:]
+		}
+		if bytes.HasPrefix(kv, prefix) {
+			return string(kv[len(prefix):]), true
+		}
+	}
+	return "", false
+}
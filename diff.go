@@ -0,0 +1,207 @@
+// Copyright 2015 The pstree Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pstree
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// Changes describes the differences between two snapshots of a process tree.
+type Changes struct {
+	Started    []int      // PIDs of the processes started since the old snapshot
+	Exited     []int      // PIDs of the processes that exited since the old snapshot
+	Reparented []Reparent // processes whose parent changed
+
+	// Rebooted reports whether the snapshots were taken during different
+	// boots of the system, according to their Tree.BootID.
+	// PIDs are then unrelated: all old processes are reported as exited
+	// and all new ones as started.
+	Rebooted bool
+}
+
+// Reparent describes a process whose parent changed between two snapshots,
+// typically because its parent exited.
+type Reparent struct {
+	PID     int // PID of the reparented process
+	OldPpid int // PID of the parent in the old snapshot
+	NewPpid int // PID of the parent in the new snapshot
+}
+
+// Diff returns the changes between the old and new snapshots of a process
+// tree.
+//
+// A PID present in both snapshots but with different start times has been
+// reused by a new process: it is reported as exited and started, rather
+// than reparented.
+// Snapshots of different boots of the system are reported as such, in
+// Changes.Rebooted.
+// All lists are sorted by PID.
+func Diff(old, new *Tree) Changes {
+	var ch Changes
+	ch.Rebooted = old.BootID != "" && new.BootID != "" && old.BootID != new.BootID
+	for pid, p0 := range old.Procs {
+		p1, ok := new.Procs[pid]
+		switch {
+		case !ok || ch.Rebooted:
+			ch.Exited = append(ch.Exited, pid)
+		case p0.Stat.Starttime != p1.Stat.Starttime:
+			ch.Exited = append(ch.Exited, pid)
+			ch.Started = append(ch.Started, pid)
+		case p0.Stat.Ppid != p1.Stat.Ppid:
+			ch.Reparented = append(ch.Reparented, Reparent{
+				PID:     pid,
+				OldPpid: p0.Stat.Ppid,
+				NewPpid: p1.Stat.Ppid,
+			})
+		}
+	}
+	for pid := range new.Procs {
+		if _, ok := old.Procs[pid]; !ok || ch.Rebooted {
+			ch.Started = append(ch.Started, pid)
+		}
+	}
+
+	sort.Ints(ch.Started)
+	sort.Ints(ch.Exited)
+	sort.Slice(ch.Reparented, func(i, j int) bool {
+		return ch.Reparented[i].PID < ch.Reparented[j].PID
+	})
+	return ch
+}
+
+// WriteDiff writes the subtree rooted at root of the new snapshot of a
+// process tree to w as box-drawn text, highlighting the changes since the
+// old snapshot, as reported by Diff:
+//
+//	  bash (pid 123)
+//	+ ├─ vim (pid 300)
+//	- ├─ make (pid 201)
+//	- │  └─ cc (pid 202)
+//	~ └─ sleep (pid 250) [reparented from 202]
+//
+// Started processes are marked with a "+", reparented ones with a "~".
+// Exited processes are taken from the old snapshot and displayed, marked
+// with a "-", under their old parent, as long as it is still running.
+// When w is a terminal, changes are also colored, and exited processes
+// struck through, unless the NO_COLOR environment variable is set.
+func WriteDiff(w io.Writer, old, new *Tree, root int) error {
+	var (
+		ch         = Diff(old, new)
+		started    = make(map[int]bool, len(ch.Started))
+		exited     = make(map[int]bool, len(ch.Exited))
+		reparented = make(map[int]int, len(ch.Reparented))
+	)
+	for _, pid := range ch.Started {
+		started[pid] = true
+	}
+	for _, pid := range ch.Exited {
+		exited[pid] = true
+	}
+	for _, r := range ch.Reparented {
+		reparented[r.PID] = r.OldPpid
+	}
+
+	// item is a process to display, followed by its children.
+	type item struct {
+		proc   Process
+		gone   bool   // whether the process is taken from the old snapshot
+		line   string // branches of the process
+		prefix string // prefix of the branches of its children
+	}
+
+	var stack []item
+	switch proc, ok := new.Procs[root]; {
+	case ok:
+		stack = append(stack, item{proc: proc})
+	case exited[root]:
+		stack = append(stack, item{proc: old.Procs[root], gone: true})
+	default:
+		return fmt.Errorf("pstree: unknown pid=%d", root)
+	}
+
+	var (
+		bw    = bufio.NewWriter(w)
+		color = isTerminal(w) && os.Getenv("NO_COLOR") == ""
+		seen  = map[int]bool{root: true} // displayed running processes
+		gone  = map[int]bool{}           // displayed exited processes
+	)
+	if stack[0].gone {
+		seen, gone = gone, seen
+	}
+	for len(stack) > 0 {
+		it := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		pid := it.proc.Stat.PID
+		mark, label := " ", fmt.Sprintf("%s (pid %d)", it.proc.Name, pid)
+		code := ""
+		switch {
+		case it.gone:
+			mark, code = "-", "\033[9;31m"
+		case started[pid]:
+			mark, code = "+", "\033[32m"
+		default:
+			if ppid, ok := reparented[pid]; ok {
+				mark, code = "~", "\033[33m"
+				label += fmt.Sprintf(" [reparented from %d]", ppid)
+			}
+		}
+		if color && code != "" {
+			label = code + label + "\033[0m"
+		}
+		fmt.Fprintf(bw, "%s %s%s\n", mark, it.line, label)
+
+		// running children, then exited ones, from the old snapshot.
+		var kids []item
+		if !it.gone {
+			for _, kid := range new.ChildProcs(pid) {
+				if !seen[kid.Stat.PID] {
+					seen[kid.Stat.PID] = true
+					kids = append(kids, item{proc: kid})
+				}
+			}
+		}
+		if it.gone || !started[pid] {
+			for _, kid := range old.ChildProcs(pid) {
+				if exited[kid.Stat.PID] && !gone[kid.Stat.PID] {
+					gone[kid.Stat.PID] = true
+					kids = append(kids, item{proc: kid, gone: true})
+				}
+			}
+		}
+
+		// push children in reverse order, so they are displayed in order.
+		for i := len(kids) - 1; i >= 0; i-- {
+			branch, indent := "├─ ", "│  "
+			if i == len(kids)-1 {
+				branch, indent = "└─ ", "   "
+			}
+			kid := kids[i]
+			kid.line = it.prefix + branch
+			kid.prefix = it.prefix + indent
+			stack = append(stack, kid)
+		}
+	}
+
+	err := bw.Flush()
+	if err != nil {
+		return fmt.Errorf("pstree: could not write tree: %w", err)
+	}
+	return nil
+}
+
+// isTerminal reports whether w is a terminal.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,31 @@
+// Copyright 2015 The pstree Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pstree
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffPIDReuse(t *testing.T) {
+	var (
+		p0 = testProc(1000, 1, "old")
+		p1 = testProc(1000, 500, "new")
+	)
+	p0.Stat.Starttime = 100
+	p1.Stat.Starttime = 200
+
+	old := newTestTree(t, testProc(1, 0, "init"), testProc(500, 1, "sh"), p0)
+	new := newTestTree(t, testProc(1, 0, "init"), testProc(500, 1, "sh"), p1)
+
+	got := Diff(old, new)
+	want := Changes{
+		Started: []int{1000},
+		Exited:  []int{1000},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("invalid changes:\ngot= %+v\nwant=%+v", got, want)
+	}
+}
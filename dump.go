@@ -0,0 +1,127 @@
+// Copyright 2015 The pstree Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pstree
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+)
+
+// dumpFile is a file under /proc/[pid] read by the scanner.
+type dumpFile struct {
+	name string
+	link bool // whether the file is a symbolic link
+}
+
+// dumpFiles lists the files under /proc/[pid] read by the scanner, besides
+// the namespaces of nsKinds and the content of the fd and task directories.
+var dumpFiles = []dumpFile{
+	{"stat", false},
+	{"comm", false},
+	{"environ", false},
+	{"cwd", true},
+	{"root", true},
+	{"exe", true},
+	{"cmdline", false},
+	{"status", false},
+	{"ns/pid", true},
+	{"schedstat", false},
+	{"cgroup", false},
+	{"loginuid", false},
+	{"sessionid", false},
+	{"limits", false},
+}
+
+// dumpDir returns the files of the process directory dir read by the
+// scanner, with all the options enabled.
+func dumpDir(dir string) []dumpFile {
+	files := append([]dumpFile(nil), dumpFiles...)
+	for _, kind := range nsKinds {
+		if kind != "pid" { // always read, already listed.
+			files = append(files, dumpFile{path.Join("ns", kind), true})
+		}
+	}
+	fds, _ := os.ReadDir(filepath.Join(dir, "fd"))
+	for _, fd := range fds {
+		files = append(files, dumpFile{path.Join("fd", fd.Name()), true})
+	}
+	tasks, _ := os.ReadDir(filepath.Join(dir, "task"))
+	for _, task := range tasks {
+		files = append(files, dumpFile{path.Join("task", task.Name(), "stat"), false})
+	}
+	return files
+}
+
+// DumpPID writes to w, for troubleshooting purposes, the raw content of each
+// file of the process pid read by the scanner, with all its options, such as
+// the file descriptors and the stat of each thread, followed by the result
+// of ParseStat on its stat file and by the result of Scan, with all the
+// options reading these files enabled.
+// Errors reading or parsing individual files are reported in the dump
+// itself.
+func DumpPID(w io.Writer, pid int) error {
+	bw := bufio.NewWriter(w)
+	dir := filepath.Join("/proc", strconv.Itoa(pid))
+
+	var stat []byte
+	for _, f := range dumpDir(dir) {
+		fname := filepath.Join(dir, filepath.FromSlash(f.name))
+		fmt.Fprintf(bw, "== %s\n", fname)
+		var (
+			data []byte
+			err  error
+		)
+		switch {
+		case f.link:
+			var link string
+			link, err = os.Readlink(fname)
+			data = []byte(link)
+		default:
+			data, err = os.ReadFile(fname)
+		}
+		if err != nil {
+			fmt.Fprintf(bw, "error: %+v\n", err)
+			continue
+		}
+		if f.name == "stat" {
+			stat = data
+		}
+		fmt.Fprintf(bw, "%q\n", data)
+	}
+
+	fmt.Fprintf(bw, "== ParseStat\n")
+	if stat != nil {
+		ps, err := ParseStat(stat)
+		dumpValue(bw, ps, err)
+	}
+
+	fmt.Fprintf(bw, "== Scan\n")
+	proc, err := Scan(pid,
+		WithSchedStat(true), WithAudit(true), WithCaps(true), WithCgroups(true),
+		WithLimits(true), WithNamespaces(true), WithFDs(true), WithThreads(true),
+		WithExeInode(true),
+	)
+	dumpValue(bw, proc, err)
+
+	return bw.Flush()
+}
+
+func dumpValue(w io.Writer, v interface{}, err error) {
+	if err != nil {
+		fmt.Fprintf(w, "error: %+v\n", err)
+	}
+	out, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		fmt.Fprintf(w, "error: %+v\n", err)
+		return
+	}
+	fmt.Fprintf(w, "%s\n", out)
+}
@@ -0,0 +1,41 @@
+// Copyright 2015 The pstree Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pstree
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestDumpPID(t *testing.T) {
+	pid := os.Getpid()
+	if _, err := os.Stat(fmt.Sprintf("/proc/%d/stat", pid)); err != nil {
+		t.Skipf("no procfs: %+v", err)
+	}
+
+	var sb strings.Builder
+	err := DumpPID(&sb, pid)
+	if err != nil {
+		t.Fatalf("could not dump pid=%d: %+v", pid, err)
+	}
+
+	dump := sb.String()
+	for _, section := range []string{
+		"stat", "status", "limits", "ns/pid", "ns/net", "fd/0",
+		fmt.Sprintf("task/%d/stat", pid),
+	} {
+		hdr := fmt.Sprintf("== /proc/%d/%s\n", pid, section)
+		if !strings.Contains(dump, hdr) {
+			t.Errorf("missing section %q", hdr)
+		}
+	}
+	for _, field := range []string{`"fdlimit"`, `"namespaces"`, `"threads"`} {
+		if !strings.Contains(dump, field) {
+			t.Errorf("missing scanned field %s", field)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2015 The pstree Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pstree
+
+import (
+	"reflect"
+	"sort"
+)
+
+// Equal reports whether the trees t and o hold the same processes, with the
+// same data and the same children, in the same order.
+// Nil and empty slices and maps are considered equal.
+func (t *Tree) Equal(o *Tree) bool {
+	return t.equal(o, false)
+}
+
+// EqualUnordered is like Equal but ignores the order of the children of
+// each process, e.g. to compare a tree with one reconstructed from a source
+// that does not preserve it.
+func (t *Tree) EqualUnordered(o *Tree) bool {
+	return t.equal(o, true)
+}
+
+func (t *Tree) equal(o *Tree, unordered bool) bool {
+	if len(t.Procs) != len(o.Procs) {
+		return false
+	}
+	for pid, p := range t.Procs {
+		q, ok := o.Procs[pid]
+		if !ok {
+			return false
+		}
+		if !reflect.DeepEqual(normalize(p, unordered), normalize(q, unordered)) {
+			return false
+		}
+	}
+	return true
+}
+
+// normalize returns p with its empty slices and maps set to nil and, if
+// sorted is set, with a sorted copy of its children.
+func normalize(p Process, sorted bool) Process {
+	if len(p.Children) == 0 {
+		p.Children = nil
+	}
+	if sorted && p.Children != nil {
+		p.Children = append([]int(nil), p.Children...)
+		sort.Ints(p.Children)
+	}
+	if len(p.Threads) == 0 {
+		p.Threads = nil
+	}
+	if p.Threads != nil {
+		threads := make([]ProcessStat, len(p.Threads))
+		for i, th := range p.Threads {
+			threads[i] = normalizeStat(th)
+		}
+		p.Threads = threads
+	}
+	p.Stat = normalizeStat(p.Stat)
+	if len(p.Labels) == 0 {
+		p.Labels = nil
+	}
+	// drop the monotonic clock reading and the location, lost or altered
+	// when encoding.
+	p.ScannedAt = p.ScannedAt.Round(0).UTC()
+	return p
+}
+
+// normalizeStat returns stat with its empty slices and maps set to nil.
+func normalizeStat(stat ProcessStat) ProcessStat {
+	if len(stat.EnvironRaw) == 0 {
+		stat.EnvironRaw = nil
+	}
+	if len(stat.CmdlineRaw) == 0 {
+		stat.CmdlineRaw = nil
+	}
+	if len(stat.NSpid) == 0 {
+		stat.NSpid = nil
+	}
+	if len(stat.Cgroups) == 0 {
+		stat.Cgroups = nil
+	}
+	if stat.Cgroups != nil {
+		cgroups := make([]Cgroup, len(stat.Cgroups))
+		for i, cg := range stat.Cgroups {
+			if len(cg.Controllers) == 0 {
+				cg.Controllers = nil
+			}
+			cgroups[i] = cg
+		}
+		stat.Cgroups = cgroups
+	}
+	if len(stat.Sockets) == 0 {
+		stat.Sockets = nil
+	}
+	if len(stat.Namespaces) == 0 {
+		stat.Namespaces = nil
+	}
+	return stat
+}
@@ -0,0 +1,68 @@
+// Copyright 2015 The pstree Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pstree
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEqualUnordered(t *testing.T) {
+	procs := []Process{
+		testProc(1, 0, "init"),
+		testProc(10, 1, "a"),
+		testProc(20, 1, "b"),
+		testProc(30, 1, "c"),
+	}
+	t1 := newTestTree(t, procs...)
+	t2 := newTestTree(t, procs...)
+	t2.SortChildren(func(a, b Process) bool { return a.Stat.PID > b.Stat.PID })
+
+	if !t1.Equal(t1) {
+		t.Fatalf("tree not equal to itself")
+	}
+	if t1.Equal(t2) {
+		t.Fatalf("trees with different children order are equal")
+	}
+	if !t1.EqualUnordered(t2) {
+		t.Fatalf("trees with different children order are not equal, unordered")
+	}
+}
+
+func TestEqualScannedAt(t *testing.T) {
+	scanned := time.Now()
+
+	p1 := testProc(1, 0, "init")
+	p1.ScannedAt = scanned
+	p2 := p1
+	p2.ScannedAt = scanned.Round(0).In(time.FixedZone("UTC+2", 2*60*60))
+
+	if !newTestTree(t, p1).Equal(newTestTree(t, p2)) {
+		t.Fatalf("trees scanned at the same time, in different locations, are not equal")
+	}
+
+	p2.ScannedAt = scanned.Add(time.Second)
+	if newTestTree(t, p1).Equal(newTestTree(t, p2)) {
+		t.Fatalf("trees scanned at different times are equal")
+	}
+}
+
+func TestEqualEmptyStat(t *testing.T) {
+	p1 := testProc(2, 0, "kthreadd")
+	p1.Stat.EnvironRaw = []byte{}
+	p1.Stat.CmdlineRaw = []byte{}
+	p1.Stat.Cgroups = []Cgroup{{Path: "/", Controllers: []string{}}}
+	p1.Stat.Sockets = []uint64{}
+	p1.Stat.Namespaces = map[string]uint64{}
+	p1.Threads = []ProcessStat{p1.Stat}
+
+	p2 := testProc(2, 0, "kthreadd")
+	p2.Stat.Cgroups = []Cgroup{{Path: "/"}}
+	p2.Threads = []ProcessStat{p2.Stat}
+
+	if !newTestTree(t, p1).Equal(newTestTree(t, p2)) {
+		t.Fatalf("trees differing by nil and empty stat fields are not equal")
+	}
+}
@@ -0,0 +1,222 @@
+// Copyright 2015 The pstree Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build linux
+// +build linux
+
+package pstree
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"syscall"
+	"time"
+)
+
+// ProcEventKind is the kind of a ProcEvent.
+type ProcEventKind int
+
+const (
+	ProcEventFork ProcEventKind = iota + 1 // a process or thread was created
+	ProcEventExec                          // a process executed a new program
+	ProcEventExit                          // a process or thread exited
+)
+
+func (k ProcEventKind) String() string {
+	switch k {
+	case ProcEventFork:
+		return "fork"
+	case ProcEventExec:
+		return "exec"
+	case ProcEventExit:
+		return "exit"
+	}
+	return fmt.Sprintf("ProcEventKind(%d)", int(k))
+}
+
+// ProcEvent is a process event, as reported by the Linux process events
+// connector.
+//
+// Events are reported for threads as well as for processes: thread events
+// have a PID, the thread ID, differing from their Tgid.
+type ProcEvent struct {
+	Kind ProcEventKind
+	PID  int // ID of the created, exec'ing or exiting thread
+	Tgid int // thread group ID, i.e. process ID, of the thread
+	Ppid int // process ID of the parent, for fork events and, since Linux 4.18, exit events
+
+	ExitCode int // exit status, as returned by wait(2), for exit events
+}
+
+// constants of linux/netlink.h, linux/connector.h and linux/cn_proc.h.
+const (
+	netlinkConnector = 11 // NETLINK_CONNECTOR
+	cnIdxProc        = 1  // CN_IDX_PROC
+	cnValProc        = 1  // CN_VAL_PROC
+
+	procCnMcastListen = 1 // PROC_CN_MCAST_LISTEN
+	procCnMcastIgnore = 2 // PROC_CN_MCAST_IGNORE
+
+	procEventFork = 0x00000001 // PROC_EVENT_FORK
+	procEventExec = 0x00000002 // PROC_EVENT_EXEC
+	procEventExit = 0x80000000 // PROC_EVENT_EXIT
+
+	nlmsgHdrLen = 16 // sizeof(struct nlmsghdr)
+	cnMsgLen    = 20 // sizeof(struct cn_msg)
+	procEvtLen  = 16 // sizeof(struct proc_event), without its data
+)
+
+// WatchEvents subscribes to the Linux process events connector and sends
+// fork, exec and exit events to the returned channel until ctx is done, or
+// the connection fails, at which point the channel is closed.
+// Other kinds of events are dropped.
+//
+// Events carry enough information to keep a tree up to date, along with
+// Scan, Tree.ReplaceProc and Tree.RemoveProc, without polling /proc.
+// Events may be lost if they are not consumed fast enough.
+//
+// WatchEvents requires the CAP_NET_ADMIN capability, and a kernel built with
+// CONFIG_PROC_EVENTS.
+func WatchEvents(ctx context.Context) (<-chan ProcEvent, error) {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, netlinkConnector)
+	if err != nil {
+		return nil, fmt.Errorf("pstree: could not create netlink socket: %w", err)
+	}
+
+	err = syscall.Bind(fd, &syscall.SockaddrNetlink{
+		Family: syscall.AF_NETLINK,
+		Groups: cnIdxProc,
+	})
+	if err != nil {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("pstree: could not bind netlink socket: %w", err)
+	}
+
+	// wake up regularly to check whether ctx is done.
+	tv := syscall.NsecToTimeval(int64(250 * time.Millisecond))
+	err = syscall.SetsockoptTimeval(fd, syscall.SOL_SOCKET, syscall.SO_RCVTIMEO, &tv)
+	if err != nil {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("pstree: could not set netlink socket timeout: %w", err)
+	}
+
+	err = sendProcCnOp(fd, procCnMcastListen)
+	if err != nil {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("pstree: could not subscribe to process events: %w", err)
+	}
+
+	evts := make(chan ProcEvent, 128)
+	go func() {
+		defer close(evts)
+		defer syscall.Close(fd)
+		defer sendProcCnOp(fd, procCnMcastIgnore)
+
+		buf := make([]byte, syscall.Getpagesize())
+		for ctx.Err() == nil {
+			n, _, err := syscall.Recvfrom(fd, buf, 0)
+			switch {
+			case err == nil:
+			case errors.Is(err, syscall.EAGAIN), errors.Is(err, syscall.EINTR):
+				continue
+			case errors.Is(err, syscall.ENOBUFS):
+				// the kernel dropped events: keep going.
+				continue
+			default:
+				return
+			}
+
+			msgs, err := syscall.ParseNetlinkMessage(buf[:n])
+			if err != nil {
+				continue
+			}
+			for _, msg := range msgs {
+				evt, ok := parseProcEvent(msg.Data)
+				if !ok {
+					continue
+				}
+				select {
+				case evts <- evt:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return evts, nil
+}
+
+// sendProcCnOp sends a proc connector operation, like PROC_CN_MCAST_LISTEN,
+// to the kernel.
+func sendProcCnOp(fd int, op uint32) error {
+	const size = nlmsgHdrLen + cnMsgLen + 4
+	var (
+		buf = make([]byte, size)
+		enc = binary.NativeEndian
+	)
+	// struct nlmsghdr
+	enc.PutUint32(buf[0:], size)
+	enc.PutUint16(buf[4:], syscall.NLMSG_DONE)
+	enc.PutUint32(buf[12:], uint32(syscall.Getpid()))
+	// struct cn_msg
+	msg := buf[nlmsgHdrLen:]
+	enc.PutUint32(msg[0:], cnIdxProc)
+	enc.PutUint32(msg[4:], cnValProc)
+	enc.PutUint16(msg[16:], 4)
+	// enum proc_cn_mcast_op
+	enc.PutUint32(msg[cnMsgLen:], op)
+
+	return syscall.Sendto(fd, buf, 0, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK})
+}
+
+// parseProcEvent parses the payload of a netlink message holding a struct
+// cn_msg and its struct proc_event.
+func parseProcEvent(data []byte) (ProcEvent, bool) {
+	enc := binary.NativeEndian
+	if len(data) < cnMsgLen+procEvtLen {
+		return ProcEvent{}, false
+	}
+	if enc.Uint32(data[0:]) != cnIdxProc || enc.Uint32(data[4:]) != cnValProc {
+		return ProcEvent{}, false
+	}
+	evt := data[cnMsgLen:]
+	what := enc.Uint32(evt[0:])
+	body := evt[procEvtLen:]
+	field := func(i int) int {
+		if len(body) < 4*(i+1) {
+			return 0
+		}
+		return int(int32(enc.Uint32(body[4*i:])))
+	}
+
+	switch what {
+	case procEventFork:
+		// parent_pid, parent_tgid, child_pid, child_tgid
+		return ProcEvent{
+			Kind: ProcEventFork,
+			PID:  field(2),
+			Tgid: field(3),
+			Ppid: field(1),
+		}, true
+	case procEventExec:
+		// process_pid, process_tgid
+		return ProcEvent{
+			Kind: ProcEventExec,
+			PID:  field(0),
+			Tgid: field(1),
+		}, true
+	case procEventExit:
+		// process_pid, process_tgid, exit_code, exit_signal, parent_pid, parent_tgid
+		return ProcEvent{
+			Kind:     ProcEventExit,
+			PID:      field(0),
+			Tgid:     field(1),
+			Ppid:     field(5),
+			ExitCode: field(2),
+		}, true
+	}
+	return ProcEvent{}, false
+}
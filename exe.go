@@ -0,0 +1,39 @@
+// Copyright 2015 The pstree Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pstree
+
+import "fmt"
+
+// WithExeInode configures whether the device and inode number of the
+// executable of each process are read, by following /proc/[pid]/exe, into
+// ProcessStat.ExeDev and ProcessStat.ExeInode.
+//
+// Unlike its path, the inode of an executable identifies it: processes
+// started from the same path but running different inodes reveal a binary
+// that was replaced in between, e.g. by an upgrade or by tampering.
+// Executables of deleted files are still identified.
+// Executables that can not be reached, e.g. for lack of permissions or for
+// kernel threads, are left out.
+// Device and inode numbers are only available on Linux.
+func WithExeInode(v bool) Option {
+	return func(cfg *config) {
+		cfg.exeInode = v
+	}
+}
+
+// readExeInode reads the device and inode number of the executable the
+// /proc/[pid]/exe link exe points to into stat.
+func readExeInode(exe string, cfg config, stat *ProcessStat) error {
+	fi, err := cfg.stat(exe)
+	switch {
+	case err == nil:
+	case tolerate(err):
+		return nil
+	default:
+		return fmt.Errorf("could not stat %s: %w", exe, err)
+	}
+	stat.ExeDev, stat.ExeInode, _ = fileID(fi)
+	return nil
+}
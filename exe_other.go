@@ -0,0 +1,15 @@
+// Copyright 2015 The pstree Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !linux
+// +build !linux
+
+package pstree
+
+import "io/fs"
+
+// fileID returns the device and inode number of a file, if known.
+func fileID(fi fs.FileInfo) (dev, ino uint64, ok bool) {
+	return 0, 0, false
+}
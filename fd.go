@@ -0,0 +1,82 @@
+// Copyright 2015 The pstree Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pstree
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// WithFDs configures whether the file descriptors of each process are
+// scanned from /proc/[pid]/fd, to collect the inode numbers of its sockets
+// into ProcessStat.Sockets.
+// Reading the file descriptors of processes of other users requires
+// privileges; they are skipped otherwise.
+func WithFDs(v bool) Option {
+	return func(cfg *config) {
+		cfg.fds = v
+	}
+}
+
+// readSockets returns the sorted inode numbers of the sockets opened by the
+// process whose procfs directory is dir.
+func readSockets(dir string, cfg config) ([]uint64, error) {
+	fddir := path.Join(dir, "fd")
+	ents, err := fs.ReadDir(cfg.fsys, fddir)
+	switch {
+	case err == nil:
+	case tolerate(err):
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("could not list %s: %w", fddir, err)
+	}
+
+	var inodes []uint64
+	for _, ent := range ents {
+		fname := path.Join(fddir, ent.Name())
+		link, err := cfg.readlink(fname)
+		switch {
+		case err == nil:
+		case tolerate(err):
+			// file descriptor closed since ReadDir.
+			continue
+		default:
+			return nil, fmt.Errorf("could not stat %s: %w", fname, err)
+		}
+		v, ok := strings.CutPrefix(link, "socket:[")
+		if !ok || !strings.HasSuffix(v, "]") {
+			continue
+		}
+		ino, err := strconv.ParseUint(strings.TrimSuffix(v, "]"), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid socket link %q: %w", fname, link, err)
+		}
+		inodes = append(inodes, ino)
+	}
+	sort.Slice(inodes, func(i, j int) bool { return inodes[i] < inodes[j] })
+	return inodes, nil
+}
+
+// ProcByInode returns the process owning the socket with the given inode
+// number, as listed in /proc/net/tcp or /proc/net/udp, and whether it was
+// found.
+// Sockets shared by several processes, e.g. after a fork, are attributed to
+// the one with the lowest PID.
+// ProcByInode only finds sockets of trees created with WithFDs.
+func (t *Tree) ProcByInode(inode uint64) (Process, bool) {
+	for _, pid := range t.pids() {
+		proc := t.Procs[pid]
+		socks := proc.Stat.Sockets
+		i := sort.Search(len(socks), func(i int) bool { return socks[i] >= inode })
+		if i < len(socks) && socks[i] == inode {
+			return proc, true
+		}
+	}
+	return Process{}, false
+}
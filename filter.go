@@ -0,0 +1,160 @@
+// Copyright 2015 The pstree Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pstree
+
+// FilterTree returns a new tree holding the processes for which keep returns
+// true, along with their ancestors so that kept processes remain connected
+// to their root.
+// The original tree is not modified.
+func (t *Tree) FilterTree(keep func(Process) bool) *Tree {
+	kept := make(map[int]bool)
+	for pid, proc := range t.Procs {
+		if !keep(proc) {
+			continue
+		}
+		for !kept[pid] {
+			kept[pid] = true
+			parent, ok := t.Procs[proc.Stat.Ppid]
+			if !ok {
+				break
+			}
+			pid, proc = parent.Stat.PID, parent
+		}
+	}
+
+	procs := make(map[int]Process, len(kept))
+	for pid := range kept {
+		proc := t.Procs[pid].Copy()
+		var children []int
+		for _, cid := range proc.Children {
+			if kept[cid] {
+				children = append(children, cid)
+			}
+		}
+		proc.Children = children
+		procs[pid] = proc
+	}
+	return &Tree{Procs: procs, BootID: t.BootID}
+}
+
+// Prune returns a new tree without the processes for which drop returns
+// true.
+// The children of a dropped process are re-attached to its nearest kept
+// ancestor, in place of the dropped process among its siblings, so the
+// tree stays connected: their Ppid is updated accordingly.
+// Kept processes without any kept ancestor become roots.
+// The original tree is not modified.
+func (t *Tree) Prune(drop func(Process) bool) *Tree {
+	dropped := make(map[int]bool)
+	for pid, proc := range t.Procs {
+		if drop(proc) {
+			dropped[pid] = true
+		}
+	}
+
+	procs := make(map[int]Process, len(t.Procs)-len(dropped))
+	for pid, proc := range t.Procs {
+		if dropped[pid] {
+			continue
+		}
+		proc = proc.Copy()
+		proc.Children = t.keptChildren(pid, dropped)
+		procs[pid] = proc
+	}
+
+	attached := make(map[int]bool, len(procs))
+	for pid, proc := range procs {
+		for _, cid := range proc.Children {
+			child := procs[cid]
+			child.Stat.Ppid = pid
+			procs[cid] = child
+			attached[cid] = true
+		}
+	}
+	for pid, proc := range procs {
+		if attached[pid] || !dropped[proc.Stat.Ppid] {
+			continue
+		}
+		// all ancestors were dropped: the process becomes a root, attached
+		// to the first ancestor absent from the original tree, if any.
+		ppid, seen := proc.Stat.Ppid, make(map[int]bool)
+		for dropped[ppid] && !seen[ppid] {
+			seen[ppid] = true
+			ppid = t.Procs[ppid].Stat.Ppid
+		}
+		if dropped[ppid] {
+			ppid = 0
+		}
+		proc.Stat.Ppid = ppid
+		procs[pid] = proc
+	}
+	return &Tree{Procs: procs, BootID: t.BootID}
+}
+
+// keptChildren returns the children of pid which are not dropped, replacing
+// dropped children with their own kept children, recursively.
+func (t *Tree) keptChildren(pid int, dropped map[int]bool) []int {
+	var (
+		kids  []int
+		seen  = map[int]bool{pid: true}
+		stack []int
+	)
+	push := func(pids []int) {
+		for i := len(pids) - 1; i >= 0; i-- {
+			stack = append(stack, pids[i])
+		}
+	}
+	push(t.Procs[pid].Children)
+	for len(stack) > 0 {
+		cid := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		child, ok := t.Procs[cid]
+		if !ok || seen[cid] {
+			continue
+		}
+		seen[cid] = true
+		if !dropped[cid] {
+			kids = append(kids, cid)
+			continue
+		}
+		push(child.Children)
+	}
+	return kids
+}
+
+// MapProcs returns a new tree holding the result of fn applied to a copy of
+// each process of t, e.g. to strip environment blobs before serializing the
+// tree or to attach labels.
+// The structure of the tree is preserved: the PID, parent PID and children
+// of each process are restored after fn is applied.
+// The original tree is not modified.
+func (t *Tree) MapProcs(fn func(Process) Process) *Tree {
+	procs := make(map[int]Process, len(t.Procs))
+	for pid, proc := range t.Procs {
+		o := fn(proc.Copy())
+		o.Stat.PID = proc.Stat.PID
+		o.Stat.Ppid = proc.Stat.Ppid
+		o.Children = append([]int(nil), proc.Children...)
+		procs[pid] = o
+	}
+	return &Tree{Procs: procs, BootID: t.BootID, Cycles: t.Cycles}
+}
+
+// Lightweight returns a copy of the tree without the bulky fields of its
+// processes: their environment, command line and working directory, raw or
+// not.
+// It is meant for monitors keeping many snapshots around, only needing the
+// identity and metrics of processes.
+// The original tree is not modified.
+func (t *Tree) Lightweight() *Tree {
+	return t.MapProcs(func(p Process) Process {
+		p.Stat.Environ = ""
+		p.Stat.Cmdline = ""
+		p.Stat.Cwd = ""
+		p.Stat.EnvironRaw = nil
+		p.Stat.CmdlineRaw = nil
+		return p
+	})
+}
@@ -0,0 +1,70 @@
+// Copyright 2015 The pstree Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pstree
+
+import (
+	"fmt"
+	"sort"
+)
+
+// pfKthread is the PF_KTHREAD bit of the kernel flags word of a process.
+const pfKthread = 0x00200000
+
+// pfNames maps the stable PF_* bits of the kernel flags word of a process to
+// their name, as defined in include/linux/sched.h.
+var pfNames = map[uint32]string{
+	0x00000002: "PF_IDLE",
+	0x00000004: "PF_EXITING",
+	0x00000020: "PF_WQ_WORKER",
+	0x00000040: "PF_FORKNOEXEC",
+	0x00000100: "PF_SUPERPRIV",
+	0x00000200: "PF_DUMPCORE",
+	0x00000400: "PF_SIGNALED",
+	0x00000800: "PF_MEMALLOC",
+	0x00001000: "PF_NPROC_EXCEEDED",
+	0x00002000: "PF_USED_MATH",
+	0x00008000: "PF_NOFREEZE",
+	0x00020000: "PF_KSWAPD",
+	pfKthread:  "PF_KTHREAD",
+	0x00400000: "PF_RANDOMIZE",
+	0x04000000: "PF_NO_SETAFFINITY",
+	0x08000000: "PF_MCE_EARLY",
+}
+
+// IsKernelThread reports whether the process is a kernel thread, i.e.
+// kthreadd (usually PID 2) or one of its children.
+// Kernel threads are identified by the PF_KTHREAD bit of their kernel
+// flags.
+func (p ProcessStat) IsKernelThread() bool {
+	return p.Flags&pfKthread != 0
+}
+
+// FlagNames returns the names of the PF_* bits set in the kernel flags word
+// of the process, e.g. "PF_KTHREAD", in increasing bit order.
+// Bits without a known name are returned together as a single hexadecimal
+// value, e.g. "0x10000000".
+//
+// The meaning, and even the value, of these flags depends on the kernel
+// version: only the long-standing ones are decoded.
+func (p ProcessStat) FlagNames() []string {
+	var names []string
+	bits := make([]uint32, 0, len(pfNames))
+	for bit := range pfNames {
+		bits = append(bits, bit)
+	}
+	sort.Slice(bits, func(i, j int) bool { return bits[i] < bits[j] })
+
+	flags := p.Flags
+	for _, bit := range bits {
+		if flags&bit != 0 {
+			names = append(names, pfNames[bit])
+			flags &^= bit
+		}
+	}
+	if flags != 0 {
+		names = append(names, fmt.Sprintf("%#x", flags))
+	}
+	return names
+}
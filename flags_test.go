@@ -0,0 +1,27 @@
+// Copyright 2015 The pstree Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pstree
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFlagNames(t *testing.T) {
+	for _, tc := range []struct {
+		flags uint32
+		want  []string
+	}{
+		{flags: 0, want: nil},
+		{flags: 0x00208040, want: []string{"PF_FORKNOEXEC", "PF_NOFREEZE", "PF_KTHREAD"}},
+		// PF_VCPU before Linux 5.12, PF_IO_WORKER since then.
+		{flags: 0x00000010, want: []string{"0x10"}},
+	} {
+		got := ProcessStat{Flags: tc.flags}.FlagNames()
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Fatalf("flags=%#x: got=%q, want=%q", tc.flags, got, tc.want)
+		}
+	}
+}
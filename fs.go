@@ -0,0 +1,67 @@
+// Copyright 2015 The pstree Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pstree
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// readLinkFS is a file system which can read symbolic links, like
+// fs.ReadLinkFS of Go 1.25.
+type readLinkFS interface {
+	fs.FS
+	ReadLink(name string) (string, error)
+}
+
+// dirFS is the file system of the directory tree rooted at a directory, as
+// returned by os.DirFS, which can also read symbolic links.
+// Errors refer to files by their full path.
+type dirFS string
+
+func (dir dirFS) Open(name string) (fs.File, error) {
+	return os.DirFS(string(dir)).Open(name)
+}
+
+func (dir dirFS) ReadFile(name string) ([]byte, error) {
+	fname, err := dir.join("readfile", name)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(fname)
+}
+
+func (dir dirFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	fname, err := dir.join("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadDir(fname)
+}
+
+func (dir dirFS) Stat(name string) (fs.FileInfo, error) {
+	fname, err := dir.join("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return os.Stat(fname)
+}
+
+func (dir dirFS) ReadLink(name string) (string, error) {
+	fname, err := dir.join("readlink", name)
+	if err != nil {
+		return "", err
+	}
+	return os.Readlink(fname)
+}
+
+// join returns the path of the named file of the file system.
+func (dir dirFS) join(op, name string) (string, error) {
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	return filepath.Join(string(dir), filepath.FromSlash(name)), nil
+}
@@ -0,0 +1,34 @@
+// Copyright 2015 The pstree Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pstree
+
+import (
+	"encoding/gob"
+	"fmt"
+	"io"
+)
+
+// EncodeGob writes the tree to w using encoding/gob, a compact binary
+// format suited for caching snapshots or sending them between Go programs.
+func (t *Tree) EncodeGob(w io.Writer) error {
+	err := gob.NewEncoder(w).Encode(t)
+	if err != nil {
+		return fmt.Errorf("pstree: could not encode tree: %w", err)
+	}
+	return nil
+}
+
+// DecodeGob reads a tree written by Tree.EncodeGob from r.
+func DecodeGob(r io.Reader) (*Tree, error) {
+	var t Tree
+	err := gob.NewDecoder(r).Decode(&t)
+	if err != nil {
+		return nil, fmt.Errorf("pstree: could not decode tree: %w", err)
+	}
+	if t.Procs == nil {
+		t.Procs = make(map[int]Process)
+	}
+	return &t, nil
+}
@@ -0,0 +1,77 @@
+// Copyright 2015 The pstree Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pstree
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestGobRoundTrip(t *testing.T) {
+	init := testProc(1, 0, "init")
+	init.Stat.CmdlineRaw = []byte("/sbin/init\x00")
+	init.Stat.EnvironRaw = []byte("HOME=/\x00")
+	init.Stat.Cgroups = []Cgroup{{ID: 0, Path: "/init.scope"}}
+	init.Stat.Namespaces = map[string]uint64{"pid": 4026531836}
+	init.Stat.Sockets = []uint64{42, 43}
+	init.Stat.SchedStat = &SchedStat{RunTime: 1, WaitTime: 2, Timeslices: 3}
+	init.Labels = map[string]string{"unit": "init.scope"}
+	init.ScannedAt = time.Now()
+
+	kthread := testProc(2, 0, "kthreadd")
+	kthread.Stat.CmdlineRaw = []byte{}
+	kthread.Stat.EnvironRaw = []byte{}
+	kthread.Stat.Flags = 0x00200000
+
+	sh := testProc(100, 1, "sh")
+	sh.Threads = []ProcessStat{sh.Stat}
+
+	for _, tc := range []struct {
+		name string
+		tree func(t *testing.T) *Tree
+	}{
+		{
+			name: "synthetic",
+			tree: func(t *testing.T) *Tree {
+				tree := newTestTree(t, init, kthread, sh)
+				tree.BootID = "8cb0c6b8-c35e-49a1-a6b5-8884b7229d0d"
+				return tree
+			},
+		},
+		{
+			name: "system",
+			tree: func(t *testing.T) *Tree {
+				tree, err := New(WithRawBlobs(true), WithThreads(true))
+				if err != nil {
+					t.Skipf("could not scan processes: %+v", err)
+				}
+				return tree
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			want := tc.tree(t)
+
+			var buf bytes.Buffer
+			err := want.EncodeGob(&buf)
+			if err != nil {
+				t.Fatalf("could not encode tree: %+v", err)
+			}
+
+			got, err := DecodeGob(&buf)
+			if err != nil {
+				t.Fatalf("could not decode tree: %+v", err)
+			}
+
+			if !got.Equal(want) {
+				t.Fatalf("round-tripped tree differs")
+			}
+			if got.BootID != want.BootID {
+				t.Fatalf("invalid boot ID: got=%q, want=%q", got.BootID, want.BootID)
+			}
+		})
+	}
+}
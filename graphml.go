@@ -0,0 +1,63 @@
+// Copyright 2015 The pstree Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pstree
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+// WriteGraphML writes the subtree rooted at root to w in the GraphML format,
+// suitable for tools like yEd or Gephi.
+// Nodes carry the PID, name and state of each process, and directed edges
+// go from parent to child.
+func (t *Tree) WriteGraphML(w io.Writer, root int) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "%s", xml.Header)
+	fmt.Fprintf(bw, "<graphml xmlns=%q>\n", "http://graphml.graphdrawing.org/xmlns")
+	fmt.Fprintf(bw, "  <key id=\"pid\" for=\"node\" attr.name=\"pid\" attr.type=\"int\"/>\n")
+	fmt.Fprintf(bw, "  <key id=\"name\" for=\"node\" attr.name=\"name\" attr.type=\"string\"/>\n")
+	fmt.Fprintf(bw, "  <key id=\"state\" for=\"node\" attr.name=\"state\" attr.type=\"string\"/>\n")
+	fmt.Fprintf(bw, "  <graph id=\"pid-%d\" edgedefault=\"directed\">\n", root)
+
+	// PIDs of the ancestors of the visited process, from root, as reached
+	// by the walk: children of malformed trees may not be those of their
+	// Ppid.
+	var stack []int
+	err := t.Walk(root, func(p Process, depth int) error {
+		stack = append(stack[:depth], p.Stat.PID)
+		fmt.Fprintf(bw, "    <node id=\"p%d\">\n", p.Stat.PID)
+		fmt.Fprintf(bw, "      <data key=\"pid\">%d</data>\n", p.Stat.PID)
+		fmt.Fprintf(bw, "      <data key=\"name\">")
+		err := xml.EscapeText(bw, []byte(p.Name))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(bw, "</data>\n")
+		fmt.Fprintf(bw, "      <data key=\"state\">")
+		err = xml.EscapeText(bw, []byte{p.Stat.State})
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(bw, "</data>\n")
+		fmt.Fprintf(bw, "    </node>\n")
+		if depth > 0 {
+			fmt.Fprintf(bw, "    <edge source=\"p%d\" target=\"p%d\"/>\n", stack[depth-1], p.Stat.PID)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("pstree: could not write GraphML: %w", err)
+	}
+
+	fmt.Fprintf(bw, "  </graph>\n</graphml>\n")
+	err = bw.Flush()
+	if err != nil {
+		return fmt.Errorf("pstree: could not write GraphML: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+// Copyright 2015 The pstree Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pstree
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestWriteGraphMLMismatchedPpid(t *testing.T) {
+	var sb strings.Builder
+	err := mismatchedTree().WriteGraphML(&sb, 1)
+	if err != nil {
+		t.Fatalf("could not write GraphML: %+v", err)
+	}
+
+	var (
+		re   = regexp.MustCompile(`<edge source="(p\d+)" target="(p\d+)"/>`)
+		got  []string
+		want = []string{"p1->p10", "p10->p11", "p11->p12"}
+	)
+	for _, m := range re.FindAllStringSubmatch(sb.String(), -1) {
+		got = append(got, m[1]+"->"+m[2])
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("invalid edges: got=%v, want=%v", got, want)
+	}
+}
@@ -0,0 +1,25 @@
+// Copyright 2015 The pstree Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pstree
+
+import "sort"
+
+// ByProcessor returns the PIDs of the processes of the tree, grouped by the
+// CPU they last ran on.
+// PIDs are sorted within each group.
+//
+// The CPU is only updated when a process is scheduled: sleeping processes
+// report the CPU they ran on last, which may be stale.
+func (t *Tree) ByProcessor() map[int][]int {
+	groups := make(map[int][]int)
+	for pid, proc := range t.Procs {
+		cpu := proc.Stat.Processor
+		groups[cpu] = append(groups[cpu], pid)
+	}
+	for _, pids := range groups {
+		sort.Ints(pids)
+	}
+	return groups
+}
@@ -0,0 +1,110 @@
+// Copyright 2015 The pstree Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pstree
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// SchemaVersion is the version of the JSON documents written by
+// Tree.WriteJSON.
+// It is incremented whenever the JSON representation of processes changes
+// in an incompatible way.
+const SchemaVersion = 1
+
+// WriteJSON writes the processes of the tree to w as a versioned JSON
+// document, with processes sorted by PID:
+//
+//	{"version":1,"generated_at":"2006-01-02T15:04:05Z","procs":[...]}
+func (t *Tree) WriteJSON(w io.Writer) error {
+	return writeJSONDoc(w, t.list())
+}
+
+// WriteJSONView is like WriteJSON but writes the JSON views of the
+// processes, with their decoded command line and, if env is set, their
+// decoded environment.
+// Environments may hold secrets: they should only be included when the
+// output is not shared.
+func (t *Tree) WriteJSONView(w io.Writer, env bool) error {
+	procs := t.list()
+	views := make([]ProcessView, len(procs))
+	for i, proc := range procs {
+		views[i] = proc.View(env)
+	}
+	return writeJSONDoc(w, views)
+}
+
+// writeJSONDoc writes procs to w as a versioned JSON document.
+func writeJSONDoc(w io.Writer, procs interface{}) error {
+	doc := struct {
+		Version     int         `json:"version"`
+		GeneratedAt string      `json:"generated_at"`
+		Procs       interface{} `json:"procs"`
+	}{
+		Version:     SchemaVersion,
+		GeneratedAt: now().UTC().Format(time.RFC3339),
+		Procs:       procs,
+	}
+	err := json.NewEncoder(w).Encode(doc)
+	if err != nil {
+		return fmt.Errorf("pstree: could not encode tree: %w", err)
+	}
+	return nil
+}
+
+// WriteJSONArray writes the processes of the tree to w as a bare JSON array,
+// sorted by PID.
+func (t *Tree) WriteJSONArray(w io.Writer) error {
+	err := json.NewEncoder(w).Encode(t.list())
+	if err != nil {
+		return fmt.Errorf("pstree: could not encode tree: %w", err)
+	}
+	return nil
+}
+
+// WriteNDJSON writes the processes of the tree to w as newline-delimited JSON,
+// one compact Process object per line, sorted by PID.
+func (t *Tree) WriteNDJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	for _, pid := range t.pids() {
+		err := enc.Encode(t.Procs[pid])
+		if err != nil {
+			return fmt.Errorf("pstree: could not encode pid=%d: %w", pid, err)
+		}
+	}
+	return nil
+}
+
+// ProcessView is the JSON view of a process: the process itself, along with
+// its decoded command line and environment, for consumers which can not
+// easily decode the base64 blobs of ProcessStat.
+type ProcessView struct {
+	Process
+	Args []string          `json:"args"`
+	Env  map[string]string `json:"env,omitempty"`
+}
+
+// View returns the JSON view of the process, with its decoded command line
+// and, if env is set, its decoded environment.
+// Blobs which can not be decoded are left out of the view.
+func (p Process) View(env bool) ProcessView {
+	v := ProcessView{Process: p}
+	v.Args, _ = p.Stat.Args()
+	if v.Args == nil {
+		v.Args = []string{}
+	}
+	if env {
+		v.Env, _ = p.Stat.Env()
+	}
+	return v
+}
+
+// list returns all the processes of the tree, sorted by PID.
+func (t *Tree) list() []Process {
+	return t.Sorted(ByPID)
+}
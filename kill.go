@@ -0,0 +1,108 @@
+// Copyright 2015 The pstree Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build linux && pstree_kill
+// +build linux,pstree_kill
+
+package pstree
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"syscall"
+)
+
+// Kill sends the signal sig to the process pid.
+// Kill fails, with an error wrapping syscall.ESRCH, if pid has exited.
+//
+// Kill, along with Tree.KillTree and Process.Signal, is only available with
+// the pstree_kill build tag: programs must opt in to signaling processes.
+//
+// Kill is a privileged and potentially destructive operation: the tree is a
+// snapshot and, by the time Kill is called, pid may have exited and been
+// reused by an unrelated process.
+// To limit the damage, Kill only signals processes that are part of the
+// tree and refuses non-positive PIDs, which kill(2) would interpret as
+// process groups (or as every process on the system, for -1).
+func (t *Tree) Kill(pid int, sig syscall.Signal) error {
+	if pid <= 0 {
+		return fmt.Errorf("pstree: refusing to signal invalid pid=%d", pid)
+	}
+	if _, ok := t.Procs[pid]; !ok {
+		return fmt.Errorf("pstree: unknown pid=%d", pid)
+	}
+	err := syscall.Kill(pid, sig)
+	if err != nil {
+		return fmt.Errorf("pstree: could not send %v to pid=%d: %w", sig, pid, err)
+	}
+	return nil
+}
+
+// KillTree sends the signal sig to all the descendants of pid and then to
+// pid itself.
+// Descendants are signaled deepest first, so a process is signaled before
+// its parent and can not be reparented to init in the meantime.
+// Unlike with Kill, processes that exited since the tree was created, pid
+// included, are ignored: they are the expected outcome of an earlier
+// KillTree.
+//
+// KillTree has the same caveats as Kill, amplified by the size of the
+// subtree: use with care.
+func (t *Tree) KillTree(pid int, sig syscall.Signal) error {
+	if pid <= 0 {
+		return fmt.Errorf("pstree: refusing to signal invalid pid=%d", pid)
+	}
+
+	type node struct {
+		pid   int
+		depth int
+	}
+	var nodes []node
+	err := t.Walk(pid, func(p Process, depth int) error {
+		nodes = append(nodes, node{p.Stat.PID, depth})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return nodes[i].depth > nodes[j].depth
+	})
+
+	// pid, at depth 0, is signaled last.
+	for _, n := range nodes {
+		err := syscall.Kill(n.pid, sig)
+		if err != nil && !errors.Is(err, syscall.ESRCH) {
+			return fmt.Errorf("pstree: could not send %v to pid=%d: %w", sig, n.pid, err)
+		}
+	}
+	return nil
+}
+
+// Signal sends the signal sig to the process.
+// Signal returns nil if the process has already exited.
+//
+// Only the process itself is signaled: use Tree.KillTree to signal a whole
+// subtree.
+// As with Tree.Kill, the PID of the process may have been reused since the
+// tree was created.
+func (p Process) Signal(sig os.Signal) error {
+	s, ok := sig.(syscall.Signal)
+	if !ok {
+		return fmt.Errorf("pstree: unsupported signal %v", sig)
+	}
+	if p.Stat.PID <= 0 {
+		return fmt.Errorf("pstree: refusing to signal invalid pid=%d", p.Stat.PID)
+	}
+	err := syscall.Kill(p.Stat.PID, s)
+	switch {
+	case errors.Is(err, syscall.ESRCH):
+		return nil
+	case err != nil:
+		return fmt.Errorf("pstree: could not send %v to pid=%d: %w", sig, p.Stat.PID, err)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+// Copyright 2015 The pstree Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build linux && pstree_kill
+// +build linux,pstree_kill
+
+package pstree
+
+import (
+	"errors"
+	"os/exec"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestKillTree(t *testing.T) {
+	cmd := exec.Command("sh", "-c", "sleep 60 & sleep 60 & wait")
+	err := cmd.Start()
+	if err != nil {
+		t.Skipf("could not start sh: %+v", err)
+	}
+	defer cmd.Process.Kill()
+	pid := cmd.Process.Pid
+
+	var tree *Tree
+	for i := 0; i < 100; i++ {
+		tree, err = New()
+		if err != nil {
+			t.Fatalf("could not create tree: %+v", err)
+		}
+		if len(tree.Procs[pid].Children) == 2 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := len(tree.Procs[pid].Children); n != 2 {
+		t.Fatalf("invalid number of children: got=%d, want=2", n)
+	}
+
+	err = tree.KillTree(pid, syscall.SIGKILL)
+	if err != nil {
+		t.Fatalf("could not kill tree: %+v", err)
+	}
+	_ = cmd.Wait()
+
+	// the whole subtree, pid included, has exited: this is not an error.
+	err = tree.KillTree(pid, syscall.SIGKILL)
+	if err != nil {
+		t.Fatalf("could not kill exited tree: %+v", err)
+	}
+
+	err = tree.Kill(pid, syscall.SIGKILL)
+	if !errors.Is(err, syscall.ESRCH) {
+		t.Fatalf("invalid error: got=%v, want=%v", err, syscall.ESRCH)
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright 2015 The pstree Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pstree
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// LimitUnlimited is the value of unlimited resource limits.
+const LimitUnlimited = -1
+
+// Limit is a resource limit of a process, as set by setrlimit(2).
+type Limit struct {
+	Soft int64 `json:"soft"` // limit enforced by the kernel, or LimitUnlimited
+	Hard int64 `json:"hard"` // ceiling for the soft limit, or LimitUnlimited
+}
+
+// WithLimits configures whether the limit on the number of open files of
+// each process is read from /proc/[pid]/limits into ProcessStat.FDLimit.
+// Limits that can not be read, e.g. for lack of permissions, are left nil.
+func WithLimits(v bool) Option {
+	return func(cfg *config) {
+		cfg.limits = v
+	}
+}
+
+// readFDLimit reads the limit on the number of open files from the limits
+// file of the process whose procfs directory is dir.
+// It returns nil if the file is not available.
+func readFDLimit(dir string, cfg config) (*Limit, error) {
+	fname := path.Join(dir, "limits")
+	data, err := cfg.readFile(fname)
+	switch {
+	case err == nil:
+	case tolerate(err):
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("could not read %s: %w", fname, err)
+	}
+
+	const name = "Max open files"
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		line, ok := strings.CutPrefix(sc.Text(), name)
+		if !ok {
+			continue
+		}
+		// soft limit, hard limit and units.
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("%s: invalid %q line %q", fname, name, sc.Text())
+		}
+		var lim Limit
+		lim.Soft, err = parseLimit(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid soft limit %q: %w", fname, fields[0], err)
+		}
+		lim.Hard, err = parseLimit(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid hard limit %q: %w", fname, fields[1], err)
+		}
+		return &lim, nil
+	}
+	return nil, nil
+}
+
+// parseLimit parses a value of /proc/[pid]/limits, like "1024" or
+// "unlimited".
+func parseLimit(v string) (int64, error) {
+	if v == "unlimited" {
+		return LimitUnlimited, nil
+	}
+	return strconv.ParseInt(v, 10, 64)
+}
@@ -0,0 +1,118 @@
+// Copyright 2015 The pstree Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pstree
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+)
+
+// Logfmt formats the stat of a process as a single line of logfmt key=value
+// pairs, suitable for structured loggers:
+//
+//	slog.Info("scanned", "proc", pstree.Logfmt(p.Stat))
+//
+// It is a distinct type, rather than a method of ProcessStat, so the JSON,
+// gob and other reflection-based encodings of ProcessStat are unaffected.
+type Logfmt ProcessStat
+
+// MarshalText implements encoding.TextMarshaler, formatting every scalar
+// field of the stat, in the order of ProcessStat and keyed by their JSON
+// names:
+//
+//	pid=123 comm=bash state=S ppid=1 pgrp=123 session=123 tty=34816 ...
+//
+// Text values are quoted when needed, and empty ones, like blobs that were
+// not read, are left out. UIDs and GIDs are written as comma-separated
+// lists.
+// Memory sizes are in bytes, rss included, and CPU times in clock ticks.
+// Slices, maps and pointers, like NSpid or SchedStat, are left out.
+func (p Logfmt) MarshalText() ([]byte, error) {
+	var buf bytes.Buffer
+	kv := func(k, v string) {
+		if buf.Len() > 0 {
+			buf.WriteByte(' ')
+		}
+		buf.WriteString(k)
+		buf.WriteByte('=')
+		if v == "" || strings.ContainsAny(v, " =\"\\") || !strconv.CanBackquote(v) {
+			v = strconv.Quote(v)
+		}
+		buf.WriteString(v)
+	}
+	str := func(k, v string) {
+		if v != "" {
+			kv(k, v)
+		}
+	}
+	num := func(k string, v int64) {
+		kv(k, strconv.FormatInt(v, 10))
+	}
+	unum := func(k string, v uint64) {
+		kv(k, strconv.FormatUint(v, 10))
+	}
+	ids := func(k string, v [4]uint32) {
+		strs := make([]string, len(v))
+		for i, id := range v {
+			strs[i] = strconv.FormatUint(uint64(id), 10)
+		}
+		kv(k, strings.Join(strs, ","))
+	}
+
+	num("pid", int64(p.PID))
+	kv("comm", p.Comm)
+	if p.State != 0 {
+		kv("state", string(p.State))
+	}
+	num("ppid", int64(p.Ppid))
+	num("pgrp", int64(p.Pgrp))
+	num("session", int64(p.Session))
+	num("tty", int64(p.TTY))
+	num("tpgid", int64(p.Tpgid))
+	unum("flags", uint64(p.Flags))
+	unum("minflt", p.Minflt)
+	unum("cminflt", p.Cminflt)
+	unum("majflt", p.Majflt)
+	unum("cmajflt", p.Cmajflt)
+	unum("utime", p.Utime)
+	unum("stime", p.Stime)
+	num("cutime", p.Cutime)
+	num("cstime", p.Cstime)
+	num("priority", p.Priority)
+	num("nice", p.Nice)
+	num("nthreads", p.Nthreads)
+	num("itrealval", p.Itrealval)
+	num("starttime", p.Starttime)
+	unum("vsize", p.Vsize)
+	num("rss", ProcessStat(p).RSSBytes())
+	num("processor", int64(p.Processor))
+	str("started_at", p.StartedAt)
+	str("environ", p.Environ)
+	str("cwd", p.Cwd)
+	str("root", p.Root)
+	str("exe", p.Exe)
+	str("cmdline", p.Cmdline)
+	unum("exe_dev", p.ExeDev)
+	unum("exe_inode", p.ExeInode)
+	num("tgid", int64(p.Tgid))
+	num("tracerpid", int64(p.TracerPid))
+	ids("uids", p.UIDs)
+	ids("gids", p.GIDs)
+	str("cpus_allowed", p.CpusAllowed)
+	num("vmrss", p.VmRSS)
+	num("rssanon", p.RssAnon)
+	num("rssfile", p.RssFile)
+	num("rssshmem", p.RssShmem)
+	unum("capinh", p.CapInh)
+	unum("capprm", p.CapPrm)
+	unum("capeff", p.CapEff)
+	num("loginuid", int64(p.LoginUID))
+	num("sessionid", int64(p.SessionID))
+	if p.Partial {
+		kv("partial", "true")
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,51 @@
+// Copyright 2015 The pstree Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pstree
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestLogfmt(t *testing.T) {
+	stat := testProc(42, 1, "my proc").Stat
+	stat.Nthreads = 3
+	stat.Cwd = "/home/bob"
+	stat.UIDs = [4]uint32{1000, 0, 1000, 1000}
+	stat.LoginUID = AuditUnset
+
+	txt, err := Logfmt(stat).MarshalText()
+	if err != nil {
+		t.Fatalf("could not marshal stat: %+v", err)
+	}
+	got := string(txt)
+	for _, kv := range []string{
+		`pid=42 comm="my proc" state=S ppid=1 `,
+		` tpgid=0 flags=0 `,
+		` nthreads=3 `,
+		` cwd=/home/bob `,
+		` uids=1000,0,1000,1000 gids=0,0,0,0 `,
+		` loginuid=-1 sessionid=0`,
+	} {
+		if !strings.Contains(got, kv) {
+			t.Errorf("missing %q in:\n%s", kv, got)
+		}
+	}
+	for _, k := range []string{"environ=", "exe=", "partial="} {
+		if strings.Contains(got, k) {
+			t.Errorf("unexpected empty field %q in:\n%s", k, got)
+		}
+	}
+
+	// ProcessStat itself keeps its JSON object encoding.
+	raw, err := json.Marshal(stat)
+	if err != nil {
+		t.Fatalf("could not marshal stat to JSON: %+v", err)
+	}
+	if !strings.HasPrefix(string(raw), `{"pid":42,`) {
+		t.Fatalf("invalid JSON encoding: %s", raw)
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright 2015 The pstree Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pstree
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WriteMermaid writes the subtree rooted at root to w as a Mermaid flowchart,
+// which GitHub and GitLab render in Markdown documents:
+//
+//	graph TD
+//	  p123["bash (pid 123)"]
+//	  p200["vim (pid 200)"]
+//	  p123 --> p200
+//
+// Characters of process names that Mermaid treats specially, like quotes,
+// are written as Mermaid entity codes.
+func (t *Tree) WriteMermaid(w io.Writer, root int) error {
+	if _, ok := t.Procs[root]; !ok {
+		return fmt.Errorf("pstree: unknown pid=%d", root)
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "graph TD\n")
+	// PIDs of the ancestors of the visited process, from root, as reached
+	// by the walk: children of malformed trees may not be those of their
+	// Ppid.
+	var stack []int
+	err := t.Walk(root, func(p Process, depth int) error {
+		stack = append(stack[:depth], p.Stat.PID)
+		fmt.Fprintf(bw, "  p%d[\"%s (pid %d)\"]\n", p.Stat.PID, mermaidEscaper.Replace(p.Name), p.Stat.PID)
+		if depth > 0 {
+			fmt.Fprintf(bw, "  p%d --> p%d\n", stack[depth-1], p.Stat.PID)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("pstree: could not write Mermaid: %w", err)
+	}
+
+	err = bw.Flush()
+	if err != nil {
+		return fmt.Errorf("pstree: could not write Mermaid: %w", err)
+	}
+	return nil
+}
+
+// mermaidEscaper escapes the characters of Mermaid labels which would end
+// the label, or be interpreted as entity codes, HTML or Markdown.
+var mermaidEscaper = strings.NewReplacer(
+	"#", "#35;",
+	`"`, "#quot;",
+	"&", "#amp;",
+	"<", "#lt;",
+	">", "#gt;",
+	"`", "#96;",
+	"\n", " ",
+	"\r", " ",
+)
@@ -0,0 +1,31 @@
+// Copyright 2015 The pstree Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pstree
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteMermaidMismatchedPpid(t *testing.T) {
+	var sb strings.Builder
+	err := mismatchedTree().WriteMermaid(&sb, 1)
+	if err != nil {
+		t.Fatalf("could not write Mermaid: %+v", err)
+	}
+
+	want := `graph TD
+  p1["init (pid 1)"]
+  p10["sshd (pid 10)"]
+  p1 --> p10
+  p11["bash (pid 11)"]
+  p10 --> p11
+  p12["vim (pid 12)"]
+  p11 --> p12
+`
+	if got := sb.String(); got != want {
+		t.Fatalf("invalid Mermaid:\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
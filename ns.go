@@ -0,0 +1,199 @@
+// Copyright 2015 The pstree Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pstree
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// nsKinds lists the kinds of namespaces read with WithNamespaces.
+var nsKinds = []string{"pid", "net", "mnt", "uts", "ipc", "user", "cgroup"}
+
+// WithNamespaces configures whether the inode numbers of the pid, net, mnt,
+// uts, ipc, user and cgroup namespaces of each process are read from
+// /proc/[pid]/ns into ProcessStat.Namespaces, keyed by kind.
+// Namespaces that can not be read, e.g. for lack of permissions, are left
+// out.
+func WithNamespaces(v bool) Option {
+	return func(cfg *config) {
+		cfg.namespaces = v
+	}
+}
+
+// readNamespaces reads the namespaces of the process whose procfs directory
+// is dir.
+func readNamespaces(dir string, cfg config) (map[string]uint64, error) {
+	nss := make(map[string]uint64, len(nsKinds))
+	for _, kind := range nsKinds {
+		fname := path.Join(dir, "ns", kind)
+		link, err := cfg.readlink(fname)
+		switch {
+		case err == nil:
+		case tolerate(err):
+			continue
+		default:
+			return nil, fmt.Errorf("could not stat %s: %w", fname, err)
+		}
+		nss[kind], err = parseNS(link)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", fname, err)
+		}
+	}
+	if len(nss) == 0 {
+		return nil, nil
+	}
+	return nss, nil
+}
+
+// ByNamespace groups the processes of the tree by their namespace of the
+// given kind (e.g. "net" or "mnt"), keyed by namespace inode number.
+// PIDs of each group are sorted.
+// Processes whose namespace is unknown are left out: ByNamespace returns an
+// empty map unless the tree was created with WithNamespaces.
+func (t *Tree) ByNamespace(kind string) map[uint64][]int {
+	groups := make(map[uint64][]int)
+	for pid, proc := range t.Procs {
+		ino, ok := proc.Stat.Namespaces[kind]
+		if !ok {
+			continue
+		}
+		groups[ino] = append(groups[ino], pid)
+	}
+	for _, pids := range groups {
+		sort.Ints(pids)
+	}
+	return groups
+}
+
+// parseNS parses the target of a /proc/[pid]/ns/* symbolic link, like
+// "pid:[4026531836]", and returns the inode number of the namespace.
+func parseNS(link string) (uint64, error) {
+	_, v, ok := strings.Cut(link, ":[")
+	if !ok || !strings.HasSuffix(v, "]") {
+		return 0, fmt.Errorf("invalid namespace link %q", link)
+	}
+	ino, err := strconv.ParseUint(strings.TrimSuffix(v, "]"), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid namespace link %q: %w", link, err)
+	}
+	return ino, nil
+}
+
+// Merge merges trees collected from possibly different PID namespaces (e.g.
+// from the host and from within its containers) into a single tree.
+//
+// Processes are identified by their own PID namespace (Process.NS) and
+// their PID in that namespace (Process.NSPid), so a container process
+// scanned both from the host and from within its container is only kept
+// once, from the first tree it appears in.
+// Processes whose namespace or namespaced PID could not be determined are
+// identified by the PID namespace of the tree they come from, and their PID
+// in that tree.
+// Processes are attached to the parent found in the first tree holding
+// both, e.g. the init process of a container to its runtime in the tree of
+// the host.
+//
+// PIDs of different namespaces may collide: a process whose PID is already
+// used in the merged tree by a process of another namespace is given a new,
+// unused, PID.
+// Processes whose parent is not part of any tree get a zero Ppid.
+func Merge(trees ...*Tree) (*Tree, error) {
+	var (
+		pids    = make(map[nsKey]int)     // identity -> merged pid
+		used    = make(map[int]bool)      // merged pids
+		procs   = make(map[nsKey]Process) // unique processes
+		parents = make(map[nsKey]nsKey)   // identity -> identity of the parent
+		order   []nsKey                   // unique processes, in merge order
+		next    = 0                       // next free merged pid
+	)
+	for i, t := range trees {
+		if t == nil {
+			return nil, fmt.Errorf("pstree: nil tree #%d", i)
+		}
+		for _, pid := range t.pids() {
+			if pid >= next {
+				next = pid + 1
+			}
+		}
+	}
+
+	for _, t := range trees {
+		ns := t.pidNS()
+		for _, pid := range t.pids() {
+			proc := t.Procs[pid]
+			k := proc.nsKey(ns)
+			if _, ok := parents[k]; !ok {
+				if parent, ok := t.Procs[proc.Stat.Ppid]; ok && proc.Stat.Ppid != pid {
+					parents[k] = parent.nsKey(ns)
+				}
+			}
+			if _, dup := pids[k]; dup {
+				continue
+			}
+			id := pid
+			if used[id] {
+				id = next
+				next++
+			}
+			used[id] = true
+			pids[k] = id
+			procs[k] = proc
+			order = append(order, k)
+		}
+	}
+
+	merged := make(map[int]Process, len(order))
+	for _, k := range order {
+		proc := procs[k].Copy()
+		if proc.Stat.Tgid == proc.Stat.PID {
+			proc.Stat.Tgid = pids[k]
+		}
+		proc.NSPid = k.pid
+		proc.Stat.PID = pids[k]
+		proc.Stat.Ppid = 0
+		if parent, ok := parents[k]; ok {
+			proc.Stat.Ppid = pids[parent]
+		}
+		proc.Children = nil
+		merged[proc.Stat.PID] = proc
+	}
+
+	return newTree(merged, newConfig(nil))
+}
+
+// nsKey identifies a process across PID namespaces.
+type nsKey struct {
+	ns  uint64 // inode number of a PID namespace
+	pid int    // PID of the process in that namespace
+}
+
+// nsKey returns the key identifying the process: its own PID namespace and
+// its PID in that namespace if known, or the PID namespace ns of its tree
+// and its PID in that tree otherwise.
+func (p Process) nsKey(ns uint64) nsKey {
+	if p.NS != 0 && p.NSPid != 0 {
+		return nsKey{p.NS, p.NSPid}
+	}
+	return nsKey{ns, p.Stat.PID}
+}
+
+// pidNS returns the inode number of the PID namespace the PIDs of the tree
+// belong to, i.e. the one of the procfs it was scanned from, or zero if it
+// can not be determined.
+// That is the namespace of the processes whose PID is not nested in another
+// namespace, or the one of the init process of the tree.
+func (t *Tree) pidNS() uint64 {
+	for _, pid := range t.pids() {
+		proc := t.Procs[pid]
+		if proc.NS != 0 && len(proc.Stat.NSpid) == 1 {
+			return proc.NS
+		}
+	}
+	return t.Procs[1].NS
+}
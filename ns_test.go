@@ -0,0 +1,84 @@
+// Copyright 2015 The pstree Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pstree
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergeContainer(t *testing.T) {
+	const (
+		hostNS      = 4026531836
+		containerNS = 4026532200
+	)
+	proc := func(pid, ppid int, name string, ns uint64, nspid ...int) Process {
+		p := testProc(pid, ppid, name)
+		p.Stat.Tgid = pid
+		p.NS = ns
+		p.Stat.NSpid = nspid
+		p.NSPid = nspid[len(nspid)-1]
+		return p
+	}
+
+	host := newTestTree(t,
+		proc(1, 0, "systemd", hostNS, 1),
+		proc(100, 1, "containerd-shim", hostNS, 100),
+		proc(200, 100, "tini", containerNS, 200, 1),
+		proc(201, 200, "nginx", containerNS, 201, 5),
+	)
+	container := newTestTree(t,
+		proc(1, 0, "tini", containerNS, 1),
+		proc(5, 1, "nginx", containerNS, 5),
+	)
+
+	type node struct {
+		name  string
+		ppid  string
+		nspid int
+	}
+	summary := func(tree *Tree) map[string]node {
+		nodes := make(map[string]node)
+		for _, p := range tree.Procs {
+			parent := tree.Procs[p.Stat.Ppid].Name
+			nodes[p.Name] = node{name: p.Name, ppid: parent, nspid: p.NSPid}
+		}
+		return nodes
+	}
+
+	want := map[string]node{
+		"systemd":         {name: "systemd", ppid: "", nspid: 1},
+		"containerd-shim": {name: "containerd-shim", ppid: "systemd", nspid: 100},
+		"tini":            {name: "tini", ppid: "containerd-shim", nspid: 1},
+		"nginx":           {name: "nginx", ppid: "tini", nspid: 5},
+	}
+
+	for _, tc := range []struct {
+		name  string
+		trees []*Tree
+	}{
+		{name: "host", trees: []*Tree{host}},
+		{name: "host+container", trees: []*Tree{host, container}},
+		{name: "container+host", trees: []*Tree{container, host}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			merged, err := Merge(tc.trees...)
+			if err != nil {
+				t.Fatalf("could not merge trees: %+v", err)
+			}
+			if len(merged.Procs) != len(want) {
+				t.Fatalf("invalid number of processes: got=%d, want=%d", len(merged.Procs), len(want))
+			}
+			if got := summary(merged); !reflect.DeepEqual(got, want) {
+				t.Fatalf("invalid merged tree:\ngot= %+v\nwant=%+v", got, want)
+			}
+			for pid, p := range merged.Procs {
+				if p.Stat.Tgid != pid {
+					t.Fatalf("invalid tgid for pid=%d: %d", pid, p.Stat.Tgid)
+				}
+			}
+		})
+	}
+}
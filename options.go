@@ -0,0 +1,302 @@
+// Copyright 2015 The pstree Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pstree
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Option configures how processes are scanned when creating a Tree.
+type Option func(*config)
+
+type config struct {
+	procDir string // mount point of procfs
+	fsys    fs.FS  // procfs, rooted at procDir unless set by NewFromFS
+
+	commFile   bool // read process names from /proc/[pid]/comm
+	argv0Name  bool // use argv[0] as process names
+	threads    bool // scan threads from /proc/[pid]/task
+	schedStat  bool // read /proc/[pid]/schedstat
+	rawBlobs   bool // keep environ and cmdline as raw bytes
+	audit      bool // read /proc/[pid]/loginuid and /proc/[pid]/sessionid
+	caps       bool // parse capability sets from /proc/[pid]/status
+	cgroups    bool // read /proc/[pid]/cgroup
+	namespaces bool // read /proc/[pid]/ns/*
+	resolve    bool // resolve symbolic links in cwd, root and exe
+	fds        bool // scan /proc/[pid]/fd
+	exeInode   bool // stat /proc/[pid]/exe
+	limits     bool // read /proc/[pid]/limits
+
+	statRetries int         // number of times truncated stat files are read again
+	maxProcs    int         // maximum number of processes scanned by New, if positive
+	cycles      CyclePolicy // handling of cycles of parent processes
+
+	limiter *rate.Limiter // throttles reads of per-process files, if any
+
+	btime time.Time // boot time of the system of fsys, zero if unknown
+}
+
+func newConfig(opts []Option) config {
+	cfg := config{
+		procDir:     "/proc",
+		statRetries: 1,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	cfg.fsys = dirFS(cfg.procDir)
+	return cfg
+}
+
+// readFile reads the named per-process file of cfg.fsys, honoring the read
+// rate limit.
+func (cfg config) readFile(name string) ([]byte, error) {
+	cfg.throttle()
+	return fs.ReadFile(cfg.fsys, name)
+}
+
+// readlink reads the named per-process symbolic link of cfg.fsys, honoring
+// the read rate limit.
+// It fails with errors.ErrUnsupported if cfg.fsys can not read symbolic
+// links.
+func (cfg config) readlink(name string) (string, error) {
+	cfg.throttle()
+	fsys, ok := cfg.fsys.(readLinkFS)
+	if !ok {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: errors.ErrUnsupported}
+	}
+	return fsys.ReadLink(name)
+}
+
+// stat returns the file information of the named per-process file of
+// cfg.fsys, following symbolic links, honoring the read rate limit.
+func (cfg config) stat(name string) (fs.FileInfo, error) {
+	cfg.throttle()
+	return fs.Stat(cfg.fsys, name)
+}
+
+// pathLink reads the named per-process symbolic link to a path, like
+// [pid]/cwd, resolving the symbolic links of its target if configured to.
+//
+// Targets are resolved as seen by the process, under its root directory
+// [pid]/root: the process may live in another mount namespace, where host
+// paths are meaningless.
+func (cfg config) pathLink(name string) (string, error) {
+	target, err := cfg.readlink(name)
+	if err != nil || !cfg.resolve {
+		return target, err
+	}
+	root := path.Join(path.Dir(name), "root")
+	rdir, err := cfg.readlink(root)
+	if err != nil {
+		return target, nil
+	}
+	// the kernel reports targets from the root directory of the reader if
+	// it can reach them, e.g. for chrooted processes, and from the one of
+	// the process, reported as "/", otherwise.
+	rel, ok := cutDir(target, rdir)
+	if !ok {
+		// out of the root directory of the process.
+		return target, nil
+	}
+	resolved, err := cfg.evalSymlinks(root, rel)
+	if err != nil {
+		// deleted, or out of reach.
+		return target, nil
+	}
+	return path.Join(rdir, resolved), nil
+}
+
+// evalSymlinks returns the slash-separated path name after the evaluation
+// of its symbolic links, which are resolved under the directory root of
+// cfg.fsys, as in a chroot.
+// The returned path is absolute, from root.
+func (cfg config) evalSymlinks(root, name string) (string, error) {
+	const maxLinks = 255 // as filepath.EvalSymlinks
+
+	var (
+		dst   []string // resolved path elements
+		todo  = strings.Split(name, "/")
+		links = 0
+	)
+	for len(todo) > 0 {
+		elem := todo[0]
+		todo = todo[1:]
+		switch elem {
+		case "", ".":
+			continue
+		case "..":
+			if len(dst) > 0 {
+				dst = dst[:len(dst)-1]
+			}
+			continue
+		}
+		dst = append(dst, elem)
+		link, err := cfg.readlink(path.Join(root, path.Join(dst...)))
+		switch {
+		case err == nil:
+		case errors.Is(err, syscall.EINVAL):
+			// not a symbolic link.
+			continue
+		default:
+			return "", err
+		}
+		links++
+		if links > maxLinks {
+			return "", fmt.Errorf("%s: too many links", name)
+		}
+		dst = dst[:len(dst)-1]
+		if path.IsAbs(link) {
+			dst = dst[:0]
+		}
+		todo = append(strings.Split(link, "/"), todo...)
+	}
+	return "/" + path.Join(dst...), nil
+}
+
+// cutDir returns the slash-separated path name relative to the directory
+// dir, and whether name is dir or one of its descendants.
+func cutDir(name, dir string) (string, bool) {
+	switch {
+	case name == dir:
+		return "", true
+	case dir == "/":
+		return strings.TrimPrefix(name, "/"), path.IsAbs(name)
+	}
+	return strings.CutPrefix(name, dir+"/")
+}
+
+func (cfg config) throttle() {
+	if cfg.limiter == nil {
+		return
+	}
+	// Wait only fails for canceled contexts, bursts larger than the
+	// limiter's or zero limits: WithReadRate never creates a limiter
+	// with a zero limit, and a burst of 1 read is always allowed.
+	_ = cfg.limiter.Wait(context.Background())
+}
+
+// WithProcDir sets the directory where procfs is mounted, "/proc" by
+// default, e.g. to scan the processes of a container or of a chroot from
+// the host.
+// System-wide information, such as the boot time used for
+// ProcessStat.StartedAt, is read from dir too.
+func WithProcDir(dir string) Option {
+	return func(cfg *config) {
+		cfg.procDir = dir
+	}
+}
+
+// WithCommFile configures whether Process.Name is read from
+// /proc/[pid]/comm instead of being extracted from /proc/[pid]/stat.
+// Both report the same name, but the comm file does not need any parsing.
+// The name from stat is used if comm can not be read.
+//
+// Either way, the kernel truncates process names to 15 characters.
+func WithCommFile(v bool) Option {
+	return func(cfg *config) {
+		cfg.commFile = v
+	}
+}
+
+// WithArgv0Name configures whether Process.Name is derived from the first
+// element of the command line of the process, instead of being its kernel
+// name.
+// This is useful for processes rewriting their argv[0] to describe their
+// role, such as postgres or nginx workers.
+//
+// The name is taken, in order of precedence, from:
+//   - the base name of argv[0], with surrounding spaces trimmed, if not empty,
+//   - /proc/[pid]/comm, with WithCommFile,
+//   - /proc/[pid]/stat.
+//
+// Kernel threads and zombies, which have no command line, thus keep their
+// kernel name.
+func WithArgv0Name(v bool) Option {
+	return func(cfg *config) {
+		cfg.argv0Name = v
+	}
+}
+
+// WithRawBlobs configures whether the contents of /proc/[pid]/environ and
+// /proc/[pid]/cmdline are stored as is in ProcessStat.EnvironRaw and
+// ProcessStat.CmdlineRaw, instead of base64-encoded in ProcessStat.Environ
+// and ProcessStat.Cmdline.
+//
+// Raw blobs avoid the encoding cost while scanning and the decoding cost in
+// ProcessStat.Args and ProcessStat.EnvValue, and take about 3/4 of the memory
+// of their base64 form.
+// Callers reading Environ or Cmdline directly will find them empty.
+// JSON output still base64-encodes the raw blobs, under different keys.
+func WithRawBlobs(v bool) Option {
+	return func(cfg *config) {
+		cfg.rawBlobs = v
+	}
+}
+
+// WithStatRetries sets the number of times /proc/[pid]/stat is read again
+// when its content is truncated, as happens when racing with the kernel on
+// very busy systems, before the process is considered to have vanished.
+// The default is to retry once.
+func WithStatRetries(n int) Option {
+	return func(cfg *config) {
+		if n < 0 {
+			n = 0
+		}
+		cfg.statRetries = n
+	}
+}
+
+// WithMaxProcs limits the number of processes scanned by New to n, to
+// bound the resources used on systems running away with processes, e.g.
+// under a fork bomb.
+// A zero or negative n, the default, means no limit.
+func WithMaxProcs(n int) Option {
+	return func(cfg *config) {
+		cfg.maxProcs = n
+	}
+}
+
+// WithResolveLinks configures whether the symbolic links found in the
+// targets of /proc/[pid]/cwd, /proc/[pid]/root and /proc/[pid]/exe are
+// resolved, as filepath.EvalSymlinks does, into ProcessStat.Cwd,
+// ProcessStat.Root and ProcessStat.Exe.
+// Links are resolved as seen by the process, through /proc/[pid]/root, so
+// that paths of processes living in another mount namespace, e.g. in a
+// container, are not resolved against unrelated files of the host.
+// Targets which can not be resolved, such as deleted files ending with
+// " (deleted)" or paths out of the root directory of the process, are kept
+// as is: the root directory itself is never resolved.
+//
+// By default, the raw targets of the links are kept, as recorded by the
+// kernel.
+func WithResolveLinks(v bool) Option {
+	return func(cfg *config) {
+		cfg.resolve = v
+	}
+}
+
+// WithReadRate limits the number of per-process files read per second while
+// scanning processes, to reduce contention on /proc on constrained systems.
+// The default, rate.Inf, does not limit reads; neither do zero or negative
+// limits.
+func WithReadRate(limit rate.Limit) Option {
+	return func(cfg *config) {
+		if limit == rate.Inf || limit <= 0 {
+			cfg.limiter = nil
+			return
+		}
+		cfg.limiter = rate.NewLimiter(limit, 1)
+	}
+}
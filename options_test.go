@@ -0,0 +1,109 @@
+// Copyright 2015 The pstree Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pstree
+
+import (
+	"io/fs"
+	"syscall"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+func TestWithReadRateUnlimited(t *testing.T) {
+	for _, limit := range []rate.Limit{rate.Inf, 0, -1} {
+		cfg := newConfig([]Option{WithReadRate(limit)})
+		if cfg.limiter != nil {
+			t.Fatalf("limit=%v: unexpected limiter", limit)
+		}
+	}
+
+	cfg := newConfig([]Option{WithReadRate(1000)})
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		cfg.throttle()
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("throttled reads took too long: %v", d)
+	}
+}
+
+// linkFS is a file system with symbolic links.
+type linkFS struct {
+	fstest.MapFS
+	links map[string]string
+}
+
+func (fsys linkFS) ReadLink(name string) (string, error) {
+	if link, ok := fsys.links[name]; ok {
+		return link, nil
+	}
+	if _, err := fs.Stat(fsys.MapFS, name); err != nil {
+		return "", err
+	}
+	return "", &fs.PathError{Op: "readlink", Path: name, Err: syscall.EINVAL}
+}
+
+func TestWithResolveLinks(t *testing.T) {
+	fsys := linkFS{
+		MapFS: fstest.MapFS{
+			"1/stat":                {Data: []byte(statLine(1, "init", 0))},
+			"100/stat":              {Data: []byte(statLine(100, "nginx", 1))},
+			"100/root/usr/sbin/x":   {Data: []byte("nginx")},
+			"100/root/srv":          {Mode: fs.ModeDir},
+			"100/root/var/www/html": {Mode: fs.ModeDir},
+			"200/stat":              {Data: []byte(statLine(200, "sh", 1))},
+			"200/root/bin/busybox":  {Data: []byte("busybox")},
+			"200/root/tmp":          {Mode: fs.ModeDir},
+		},
+		links: map[string]string{
+			"1/cwd":  "/",
+			"1/root": "/",
+			"1/exe":  "/sbin/init",
+
+			// in another mount namespace: /srv/www is only a symbolic link
+			// in the container.
+			"100/cwd":                 "/srv/www/html",
+			"100/root":                "/",
+			"100/exe":                 "/usr/sbin/nginx",
+			"100/root/srv/www":        "../var/www",
+			"100/root/usr/sbin/nginx": "x",
+
+			// chrooted under /jail.
+			"200/cwd":         "/jail/tmp",
+			"200/root":        "/jail",
+			"200/exe":         "/jail/bin/sh",
+			"200/root/bin/sh": "/bin/busybox",
+		},
+	}
+
+	for _, tc := range []struct {
+		pid            int
+		resolve        bool
+		cwd, root, exe string
+	}{
+		{pid: 1, cwd: "/", root: "/", exe: "/sbin/init"},
+		{pid: 1, resolve: true, cwd: "/", root: "/", exe: "/sbin/init"},
+		{pid: 100, cwd: "/srv/www/html", root: "/", exe: "/usr/sbin/nginx"},
+		{pid: 100, resolve: true, cwd: "/var/www/html", root: "/", exe: "/usr/sbin/x"},
+		{pid: 200, cwd: "/jail/tmp", root: "/jail", exe: "/jail/bin/sh"},
+		{pid: 200, resolve: true, cwd: "/jail/tmp", root: "/jail", exe: "/jail/bin/busybox"},
+	} {
+		tree, err := NewFromFS(fsys, WithResolveLinks(tc.resolve))
+		if err != nil {
+			t.Fatalf("could not create tree: %+v", err)
+		}
+		stat := tree.Procs[tc.pid].Stat
+		if stat.Cwd != tc.cwd || stat.Root != tc.root || stat.Exe != tc.exe {
+			t.Errorf("pid=%d, resolve=%v: invalid links:\ngot= cwd=%q root=%q exe=%q\nwant=cwd=%q root=%q exe=%q",
+				tc.pid, tc.resolve,
+				stat.Cwd, stat.Root, stat.Exe,
+				tc.cwd, tc.root, tc.exe,
+			)
+		}
+	}
+}
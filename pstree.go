@@ -3,30 +3,75 @@
 // license that can be found in the LICENSE file.
 
 // Package pstree provides an API to retrieve the process tree from procfs.
+//
+// Methods sending signals to processes, like Tree.KillTree, are only
+// available on Linux with the pstree_kill build tag:
+//
+//	go build -tags pstree_kill
 package pstree // import "github.com/sbinet/pstree"
 
 import (
 	"encoding/base64"
 	"errors"
 	"fmt"
-	"io/ioutil"
+	"io/fs"
 	"os"
-	"path/filepath"
+	"path"
 	"sort"
 	"strconv"
 	"strings"
+	"syscall"
+	"time"
 )
 
+// ErrTruncated is returned by New, along with a partial tree, when the
+// system holds more processes than allowed by WithMaxProcs.
+var ErrTruncated = errors.New("pstree: too many processes, tree truncated")
+
 // New returns the whole system process tree.
-func New() (*Tree, error) {
-	files, err := filepath.Glob("/proc/[0-9]*")
+//
+// When /proc is mounted with hidepid=1 or hidepid=2, only the processes
+// visible to the caller are scanned: the resulting tree is then partial,
+// processes whose parent is hidden being roots of the tree.
+//
+// When the number of processes exceeds the limit set with WithMaxProcs, New
+// returns the tree of the processes scanned so far, along with ErrTruncated.
+// That tree is partial in the same way.
+func New(opts ...Option) (*Tree, error) {
+	return newFromConfig(newConfig(opts))
+}
+
+// NewFromFS returns the whole process tree of the procfs file system fsys,
+// as New does with /proc.
+//
+// fsys may be os.DirFS("/proc"), an embedded snapshot of procfs, or an
+// fstest.MapFS, with files like "1/stat" or "1/status".
+// Symbolic links, like "1/exe", are only read when fsys provides a
+// ReadLink(name string) (string, error) method, as fs.ReadLinkFS does:
+// they are skipped otherwise.
+// WithProcDir is ignored, and system-wide information, such as the boot
+// time, is still read from /proc.
+func NewFromFS(fsys fs.FS, opts ...Option) (*Tree, error) {
+	cfg := newConfig(opts)
+	cfg.fsys = fsys
+	return newFromConfig(cfg)
+}
+
+func newFromConfig(cfg config) (*Tree, error) {
+	cfg.btime = readBootTime(cfg)
+	files, err := fs.Glob(cfg.fsys, "[0-9]*")
 	if err != nil {
-		return nil, fmt.Errorf("pstree: could not list pid files under /proc: %w", err)
+		return nil, fmt.Errorf("pstree: could not list pid files: %w", err)
 	}
 
+	var truncated bool
 	procs := make(map[int]Process, len(files))
 	for _, dir := range files {
-		proc, err := scan(dir)
+		if cfg.maxProcs > 0 && len(procs) >= cfg.maxProcs {
+			truncated = true
+			break
+		}
+		proc, err := scan(dir, cfg)
 		if err != nil {
 			return nil, fmt.Errorf("could not scan %s: %w", dir, err)
 		}
@@ -37,20 +82,88 @@ func New() (*Tree, error) {
 		procs[proc.Stat.PID] = proc
 	}
 
+	tree, err := newTree(procs, cfg)
+	if err != nil {
+		return nil, err
+	}
+	tree.BootID = readBootID(cfg)
+	if truncated {
+		return tree, ErrTruncated
+	}
+	return tree, nil
+}
+
+// NewForPIDs returns the process tree made of the processes pids and,
+// transitively, of their parents, so the tree is connected up to a root.
+// Only these processes are scanned.
+// PIDs that do not exist are skipped.
+func NewForPIDs(pids []int, opts ...Option) (*Tree, error) {
+	cfg := newConfig(opts)
+	cfg.btime = readBootTime(cfg)
+	procs := make(map[int]Process, len(pids))
+	queue := append([]int(nil), pids...)
+	for len(queue) > 0 {
+		pid := queue[0]
+		queue = queue[1:]
+		if _, dup := procs[pid]; dup || pid <= 0 {
+			continue
+		}
+
+		dir := strconv.Itoa(pid)
+		proc, err := scan(dir, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("could not scan %s: %w", dir, err)
+		}
+		if proc.Stat.PID == 0 {
+			// process does not exist (anymore).
+			continue
+		}
+		procs[pid] = proc
+		if proc.Stat.Ppid != 0 {
+			queue = append(queue, proc.Stat.Ppid)
+		}
+	}
+
+	tree, err := newTree(procs, cfg)
+	if err != nil {
+		return nil, err
+	}
+	tree.BootID = readBootID(cfg)
+	return tree, nil
+}
+
+// newTree links the processes to their parent and returns the resulting tree.
+// Processes whose parent is absent, e.g. hidden by the hidepid mount option
+// of /proc, are left as roots of the tree, with their Ppid untouched.
+// Cycles of parent processes are handled according to cfg.
+func newTree(procs map[int]Process, cfg config) (*Tree, error) {
 	for pid, proc := range procs {
 		if proc.Stat.Ppid == 0 {
 			continue
 		}
+		if proc.Stat.Ppid == pid {
+			// self-parented process, from a corrupted snapshot: a cycle
+			// of one process, handled below.
+			continue
+		}
 		parent, ok := procs[proc.Stat.Ppid]
 		if !ok {
-			return nil, fmt.Errorf("pstree: parent pid=%d of pid=%d does not exist",
-				proc.Stat.Ppid, pid,
-			)
+			continue
 		}
 		parent.Children = append(parent.Children, pid)
 		procs[parent.Stat.PID] = parent
 	}
 
+	cycles := findCycles(procs)
+	if len(cycles) > 0 {
+		switch cfg.cycles {
+		case CycleError:
+			return nil, cycleError(cycles)
+		default:
+			breakCycles(procs, cycles)
+		}
+	}
+
 	for pid, proc := range procs {
 		if len(proc.Children) > 0 {
 			sort.Ints(proc.Children)
@@ -59,23 +172,27 @@ func New() (*Tree, error) {
 	}
 
 	tree := &Tree{
-		Procs: procs,
+		Procs:  procs,
+		Cycles: cycles,
 	}
-	return tree, err
+	return tree, nil
 }
 
 const (
-	// statfmt is the stat format as described in proc.5.html
-	// note that the first 2 fields "pid" and "(comm)" are dealt with separately
-	// and are thus not specified in statfmt below.
-	statfmt = "%c %d %d %d %d %d %d %d %d %d %d %d %d %d %d %d %d %d %d %d %d %d"
+	// statFields is the number of fields of /proc/[pid]/stat that are
+	// parsed after the "pid" and "(comm)" ones, as described in proc.5.html.
+	statFields = 22
+
+	// processorField is the index of the "processor" field, counting from
+	// the "state" field.
+	processorField = 36
 )
 
 // ProcessStat contains process information.
 // see: http://man7.org/linux/man-pages/man5/proc.5.html
 type ProcessStat struct {
 	PID       int    `json:"pid"`       // process ID
-	Comm      string `json:"comm"`      // filename of the executable in parentheses
+	Comm      string `json:"comm"`      // filename of the executable, truncated to 15 characters
 	State     byte   `json:"state"`     // process state
 	Ppid      int    `json:"ppid"`      // pid of the parent process
 	Pgrp      int    `json:"pgrp"`      // process group ID of the process
@@ -98,97 +215,476 @@ type ProcessStat struct {
 	Starttime int64  `json:"starttime"` // time the process started after system boot in clock ticks
 	Vsize     uint64 `json:"vsize"`     // virtual memory size in bytes
 	RSS       int64  `json:"rss"`       // resident set size: number of pages the process has in real memory
+	Processor int    `json:"processor"` // CPU number last executed on
+
+	StartedAt string `json:"started_at,omitempty"` // time the process started (RFC 3339), empty if the boot time is unknown
 
 	Environ string `json:"environ"` // environment for the process
 	Cwd     string `json:"cwd"`     // current working directory for the process
+	Root    string `json:"root"`    // root directory for the process, as set by chroot or a mount namespace
+	Exe     string `json:"exe"`     // path of the executable of the process, with a " (deleted)" suffix if it was removed
 	Cmdline string `json:"cmdline"` // complete command line for the process
+
+	EnvironRaw []byte `json:"environ_raw,omitempty"` // environment for the process, as read (see WithRawBlobs)
+	CmdlineRaw []byte `json:"cmdline_raw,omitempty"` // complete command line for the process, as read (see WithRawBlobs)
+
+	ExeDev   uint64 `json:"exe_dev,omitempty"`   // device holding the executable of the process (see WithExeInode)
+	ExeInode uint64 `json:"exe_inode,omitempty"` // inode number of the executable of the process (see WithExeInode)
+
+	Tgid      int       `json:"tgid"`      // thread group ID, from /proc/[pid]/status
+	TracerPid int       `json:"tracerpid"` // PID of the process tracing this one, or 0, from /proc/[pid]/status
+	UIDs      [4]uint32 `json:"uids"`      // real, effective, saved set and filesystem UIDs, from /proc/[pid]/status
+	GIDs      [4]uint32 `json:"gids"`      // real, effective, saved set and filesystem GIDs, from /proc/[pid]/status
+	NSpid     []int     `json:"nspids"`    // PIDs of the process in each of its nested PID namespaces, from the one of procfs inwards, from /proc/[pid]/status (Linux >= 4.1)
+
+	CpusAllowed string `json:"cpus_allowed,omitempty"` // list of CPUs the process may run on, e.g. "0-3,8", from /proc/[pid]/status
+
+	VmRSS    int64 `json:"vmrss"`    // resident set size in bytes, from /proc/[pid]/status
+	RssAnon  int64 `json:"rssanon"`  // resident anonymous memory in bytes, from /proc/[pid]/status (Linux >= 4.5)
+	RssFile  int64 `json:"rssfile"`  // resident file mappings in bytes, from /proc/[pid]/status (Linux >= 4.5)
+	RssShmem int64 `json:"rssshmem"` // resident shared memory in bytes, from /proc/[pid]/status (Linux >= 4.5)
+
+	CapInh uint64 `json:"capinh,omitempty"` // inheritable capability set (see WithCaps)
+	CapPrm uint64 `json:"capprm,omitempty"` // permitted capability set (see WithCaps)
+	CapEff uint64 `json:"capeff,omitempty"` // effective capability set (see WithCaps)
+
+	LoginUID  int `json:"loginuid"`  // audit login UID, or AuditUnset if unset or not read (see WithAudit)
+	SessionID int `json:"sessionid"` // audit session ID, or AuditUnset if unset or not read (see WithAudit)
+
+	Partial bool `json:"partial,omitempty"` // whether /proc/[pid]/stat was malformed and only partially parsed
+
+	SchedStat *SchedStat `json:"schedstat,omitempty"` // scheduler statistics (see WithSchedStat)
+	FDLimit   *Limit     `json:"fdlimit,omitempty"`   // limit on the number of open files (see WithLimits)
+	Cgroups   []Cgroup   `json:"cgroups,omitempty"`   // control groups (see WithCgroups)
+
+	Namespaces map[string]uint64 `json:"namespaces,omitempty"` // inode numbers of namespaces, by kind (see WithNamespaces)
+	Sockets    []uint64          `json:"sockets,omitempty"`    // sorted inode numbers of opened sockets (see WithFDs)
 }
 
-func scan(dir string) (Process, error) {
-	stat := filepath.Join(dir, "stat")
-	data, err := ioutil.ReadFile(stat)
+// Scan scans the process pid, as New would, without linking it to its parent
+// or children.
+func Scan(pid int, opts ...Option) (Process, error) {
+	cfg := newConfig(opts)
+	cfg.btime = readBootTime(cfg)
+	proc, err := scan(strconv.Itoa(pid), cfg)
 	if err != nil {
-		// process vanished since Glob.
-		return Process{}, nil
+		return proc, fmt.Errorf("pstree: could not scan pid=%d: %w", pid, err)
 	}
-	// extracting the name of the process, enclosed in matching parentheses.
-	info := strings.FieldsFunc(string(data), func(r rune) bool {
-		return r == '(' || r == ')'
-	})
-
-	if len(info) != 3 {
-		return Process{}, fmt.Errorf("%s: file format invalid", stat)
+	if proc.Stat.PID == 0 {
+		return proc, fmt.Errorf("pstree: unknown pid=%d", pid)
 	}
+	return proc, nil
+}
 
-	for i, v := range info {
-		info[i] = strings.TrimSpace(v)
+func scan(dir string, cfg config) (Process, error) {
+	stat, ok, err := readStat(path.Join(dir, "stat"), cfg)
+	if err != nil || !ok {
+		return Process{}, err
 	}
+	proc := Process{Stat: stat, ScannedAt: now()}
 
-	var proc Process
-	proc.Stat.PID, err = strconv.Atoi(info[0])
-	if err != nil {
-		return Process{}, fmt.Errorf("%s: invalid pid format %q: %w", stat, info[0], err)
-	}
-	proc.Stat.Comm = info[1]
-
-	_, err = fmt.Sscanf(
-		info[2], statfmt,
-		&proc.Stat.State,
-		&proc.Stat.Ppid, &proc.Stat.Pgrp, &proc.Stat.Session,
-		&proc.Stat.TTY, &proc.Stat.Tpgid, &proc.Stat.Flags,
-		&proc.Stat.Minflt, &proc.Stat.Cminflt, &proc.Stat.Majflt, &proc.Stat.Cmajflt,
-		&proc.Stat.Utime, &proc.Stat.Stime,
-		&proc.Stat.Cutime, &proc.Stat.Cstime,
-		&proc.Stat.Priority,
-		&proc.Stat.Nice,
-		&proc.Stat.Nthreads,
-		&proc.Stat.Itrealval, &proc.Stat.Starttime,
-		&proc.Stat.Vsize, &proc.Stat.RSS,
-	)
-	if err != nil {
-		return proc, fmt.Errorf("could not parse file %s: %w", stat, err)
+	if !cfg.btime.IsZero() {
+		start := cfg.btime.Add(ticks(proc.Stat.Starttime))
+		proc.Stat.StartedAt = start.UTC().Format(time.RFC3339)
 	}
 
-	environ := filepath.Join(dir, "environ")
-	env, err := os.ReadFile(environ)
+	environ := path.Join(dir, "environ")
+	env, err := cfg.readFile(environ)
 	switch {
+	case err == nil && cfg.rawBlobs:
+		proc.Stat.EnvironRaw = env
 	case err == nil:
 		proc.Stat.Environ = base64.StdEncoding.EncodeToString(env)
-	default:
+	case !tolerate(err):
+		return proc, fmt.Errorf("could not parse file %s: %w", environ, err)
+	}
+
+	cwd := path.Join(dir, "cwd")
+	pwd, err := cfg.pathLink(cwd)
+	switch {
+	case err == nil:
+		proc.Stat.Cwd = pwd
+	case !tolerate(err):
+		return proc, fmt.Errorf("could not stat %s: %w", cwd, err)
+	}
+
+	root := path.Join(dir, "root")
+	rdir, err := cfg.pathLink(root)
+	switch {
+	case err == nil:
+		proc.Stat.Root = rdir
+	case !tolerate(err):
+		return proc, fmt.Errorf("could not stat %s: %w", root, err)
+	}
+
+	exe := path.Join(dir, "exe")
+	bin, err := cfg.pathLink(exe)
+	switch {
+	case err == nil:
+		proc.Stat.Exe = bin
+	case !tolerate(err):
+		return proc, fmt.Errorf("could not stat %s: %w", exe, err)
+	}
+
+	if cfg.exeInode {
+		err = readExeInode(exe, cfg, &proc.Stat)
 		if err != nil {
-			if !errors.Is(err, os.ErrPermission) {
-				return proc, fmt.Errorf("could not parse file %s: %w", environ, err)
-			}
+			return proc, err
 		}
 	}
 
-	cwd := filepath.Join(dir, "cwd")
-	pwd, err := os.Readlink(cwd)
+	cmdline := path.Join(dir, "cmdline")
+	args, err := cfg.readFile(cmdline)
 	switch {
+	case err == nil && cfg.rawBlobs:
+		proc.Stat.CmdlineRaw = args
 	case err == nil:
-		proc.Stat.Cwd = pwd
-	default:
+		proc.Stat.Cmdline = base64.StdEncoding.EncodeToString(args)
+	case !tolerate(err):
+		return proc, fmt.Errorf("could not read %s: %w", cmdline, err)
+	}
+
+	status := path.Join(dir, "status")
+	data, err := cfg.readFile(status)
+	switch {
+	case err == nil:
+		err = parseStatus(&proc.Stat, data)
 		if err != nil {
-			if !errors.Is(err, os.ErrPermission) {
-				return proc, fmt.Errorf("could not stat %s: %w", cwd, err)
+			return proc, fmt.Errorf("%s: %w", status, err)
+		}
+		if n := len(proc.Stat.NSpid); n > 0 {
+			proc.NSPid = proc.Stat.NSpid[n-1]
+		}
+		if cfg.caps {
+			err = parseCaps(&proc.Stat, data)
+			if err != nil {
+				return proc, fmt.Errorf("%s: %w", status, err)
 			}
 		}
+	case !tolerate(err):
+		return proc, fmt.Errorf("could not read %s: %w", status, err)
 	}
 
-	cmdline := filepath.Join(dir, "cmdline")
-	args, err := os.ReadFile(cmdline)
-	if err != nil {
-		return proc, fmt.Errorf("could not read %s: %w", cmdline, err)
+	pidns := path.Join(dir, "ns", "pid")
+	link, err := cfg.readlink(pidns)
+	switch {
+	case err == nil:
+		proc.NS, err = parseNS(link)
+		if err != nil {
+			return proc, fmt.Errorf("%s: %w", pidns, err)
+		}
+	case !tolerate(err):
+		return proc, fmt.Errorf("could not stat %s: %w", pidns, err)
+	}
+
+	if cfg.namespaces {
+		proc.Stat.Namespaces, err = readNamespaces(dir, cfg)
+		if err != nil {
+			return proc, err
+		}
+	}
+
+	if cfg.fds {
+		proc.Stat.Sockets, err = readSockets(dir, cfg)
+		if err != nil {
+			return proc, err
+		}
+	}
+
+	if cfg.schedStat {
+		proc.Stat.SchedStat, err = readSchedStat(dir, cfg)
+		if err != nil {
+			return proc, err
+		}
+	}
+
+	if cfg.cgroups {
+		proc.Stat.Cgroups, err = readCgroups(dir, cfg)
+		if err != nil {
+			return proc, err
+		}
+	}
+
+	// a zero login UID would mean root: audit IDs that are not read are
+	// reported as unset.
+	proc.Stat.LoginUID, proc.Stat.SessionID = AuditUnset, AuditUnset
+	if cfg.audit {
+		err = readAudit(dir, cfg, &proc.Stat)
+		if err != nil {
+			return proc, err
+		}
+	}
+
+	if cfg.limits {
+		proc.Stat.FDLimit, err = readFDLimit(dir, cfg)
+		if err != nil {
+			return proc, err
+		}
+	}
+
+	if cfg.threads {
+		proc.Threads, err = scanThreads(dir, cfg)
+		if err != nil {
+			return proc, err
+		}
+	}
+
+	if proc.Stat.Partial {
+		// the name can not be reliably extracted from malformed content.
+		comm, err := cfg.readFile(path.Join(dir, "comm"))
+		if err == nil {
+			proc.Stat.Comm = strings.TrimSuffix(string(comm), "\n")
+		}
 	}
-	proc.Stat.Cmdline = base64.StdEncoding.EncodeToString(args)
 
 	proc.Name = proc.Stat.Comm
+	if cfg.commFile {
+		comm, err := cfg.readFile(path.Join(dir, "comm"))
+		if err == nil {
+			proc.Name = strings.TrimSuffix(string(comm), "\n")
+		}
+	}
+	if cfg.argv0Name {
+		if name := argv0(proc.Stat); name != "" {
+			proc.Name = name
+		}
+	}
+
 	return proc, nil
 }
 
+// readStat reads and parses the stat file fname.
+// Truncated content is read again, up to cfg.statRetries times, before
+// considering the process has vanished.
+// readStat returns false if the process vanished.
+func readStat(fname string, cfg config) (ProcessStat, bool, error) {
+	for retry := 0; ; retry++ {
+		data, err := cfg.readFile(fname)
+		if err != nil {
+			// process vanished since Glob.
+			return ProcessStat{}, false, nil
+		}
+		stat, err := ParseStat(data)
+		switch {
+		case err == nil:
+			return stat, true, nil
+		case !errors.Is(err, errShortStat):
+			return stat, false, fmt.Errorf("%s: %w", fname, err)
+		case retry >= cfg.statRetries:
+			// process vanished while we were reading its stat file.
+			return ProcessStat{}, false, nil
+		}
+	}
+}
+
+// errShortStat is returned by ParseStat when the content of a stat file is
+// truncated, as happens when racing with an exiting process.
+var errShortStat = errors.New("truncated stat content")
+
+// ParseStat parses the content of a /proc/[pid]/stat file.
+// Only the fields of ProcessStat found in that file are set.
+// Malformed, but complete, content without a parenthesized name is parsed on
+// a best-effort basis, with ProcessStat.Partial set: fields which can not be
+// parsed are then left zero, without error.
+func ParseStat(data []byte) (ProcessStat, error) {
+	var stat ProcessStat
+
+	if !strings.Contains(string(data), "(") && strings.HasSuffix(string(data), "\n") {
+		// complete, but malformed, content.
+		return parsePartialStat(data)
+	}
+
+	// extracting the name of the process, enclosed in parentheses.
+	// The name may itself hold parentheses: as procps does, it spans from
+	// the first opening parenthesis to the last closing one.
+	str := string(data)
+	beg := strings.IndexByte(str, '(')
+	end := strings.LastIndexByte(str, ')')
+	if beg < 0 || end < beg {
+		return stat, errShortStat
+	}
+
+	fields := strings.Fields(str[end+1:])
+	if len(fields) < statFields {
+		return stat, errShortStat
+	}
+
+	var err error
+	pid := strings.TrimSpace(str[:beg])
+	stat.PID, err = strconv.Atoi(pid)
+	if err != nil {
+		return stat, fmt.Errorf("invalid pid format %q: %w", pid, err)
+	}
+	stat.Comm = str[beg+1 : end]
+
+	p := statParser{fields: fields}
+	err = p.parse(&stat)
+	return stat, err
+}
+
+// parsePartialStat parses the content of a /proc/[pid]/stat file lacking
+// the parentheses around the "comm" field, as seen on some restricted
+// setups.
+// The name of the process is made of the words between the PID and the
+// first single-letter field followed by a number, taken as the state and
+// the PID of the parent: the fields that follow the name are parsed on a
+// best-effort basis, those which can not be parsed being left zero.
+// The returned stat is flagged as partial.
+func parsePartialStat(data []byte) (ProcessStat, error) {
+	stat := ProcessStat{Partial: true}
+
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return stat, errShortStat
+	}
+
+	var err error
+	stat.PID, err = strconv.Atoi(fields[0])
+	if err != nil {
+		return stat, fmt.Errorf("invalid pid format %q: %w", fields[0], err)
+	}
+	fields = fields[1:]
+
+	state := -1
+	for i, f := range fields {
+		if len(f) != 1 || !('A' <= f[0] && f[0] <= 'Z' || 'a' <= f[0] && f[0] <= 'z') {
+			continue
+		}
+		if i+1 < len(fields) {
+			if _, err := strconv.Atoi(fields[i+1]); err != nil {
+				continue
+			}
+		}
+		state = i
+		break
+	}
+	if state < 0 {
+		// the fields can not be told apart from the name.
+		return stat, nil
+	}
+	stat.Comm = strings.Join(fields[:state], " ")
+
+	p := statParser{fields: fields[state:]}
+	_ = p.parse(&stat) // best-effort: failing fields are left zero.
+	return stat, nil
+}
+
+// statParser parses the fields of a /proc/[pid]/stat file that follow the
+// "(comm)" one.
+// Its methods record the first error encountered, which identifies the
+// field that could not be parsed.
+// Missing fields are parsed as zero.
+type statParser struct {
+	fields []string
+	err    error
+}
+
+// parse parses the fields into stat.
+// Fields are parsed according to their width in the kernel, as documented by
+// the scanf(3) conversion of proc(5): "%d" and "%u" fields are 32-bit wide,
+// "%lu" and "%ld" ones may be 32- or 64-bit wide depending on the
+// architecture of the kernel, and are thus parsed as 64-bit values, like
+// "%llu" ones.
+// Go fields are wide enough on all architectures: int fields only hold
+// 32-bit values.
+func (p *statParser) parse(stat *ProcessStat) error {
+	stat.State = p.byte(0, "state")                              // %c
+	stat.Ppid = int(p.int(1, "ppid", 32))                        // %d
+	stat.Pgrp = int(p.int(2, "pgrp", 32))                        // %d
+	stat.Session = int(p.int(3, "session", 32))                  // %d
+	stat.TTY = int(p.int(4, "tty_nr", 32))                       // %d
+	stat.Tpgid = int(p.int(5, "tpgid", 32))                      // %d
+	stat.Flags = uint32(p.uint(6, "flags", 32))                  // %u
+	stat.Minflt = p.uint(7, "minflt", 64)                        // %lu
+	stat.Cminflt = p.uint(8, "cminflt", 64)                      // %lu
+	stat.Majflt = p.uint(9, "majflt", 64)                        // %lu
+	stat.Cmajflt = p.uint(10, "cmajflt", 64)                     // %lu
+	stat.Utime = p.uint(11, "utime", 64)                         // %lu
+	stat.Stime = p.uint(12, "stime", 64)                         // %lu
+	stat.Cutime = p.int(13, "cutime", 64)                        // %ld
+	stat.Cstime = p.int(14, "cstime", 64)                        // %ld
+	stat.Priority = p.int(15, "priority", 64)                    // %ld
+	stat.Nice = p.int(16, "nice", 64)                            // %ld
+	stat.Nthreads = p.int(17, "num_threads", 64)                 // %ld
+	stat.Itrealval = p.int(18, "itrealvalue", 64)                // %ld
+	stat.Starttime = p.int(19, "starttime", 64)                  // %llu, fits in 63 bits
+	stat.Vsize = p.uint(20, "vsize", 64)                         // %lu
+	stat.RSS = p.int(21, "rss", 64)                              // %ld
+	stat.Processor = int(p.int(processorField, "processor", 32)) // %d
+	return p.err
+}
+
+func (p *statParser) fail(i int, name string, err error) {
+	if p.err != nil {
+		return
+	}
+	// fields are numbered from 1 in proc(5), starting with "pid" and "comm".
+	p.err = fmt.Errorf("could not parse stat field %d (%s) %q: %w", i+3, name, p.fields[i], err)
+}
+
+func (p *statParser) byte(i int, name string) byte {
+	if i >= len(p.fields) {
+		return 0
+	}
+	if len(p.fields[i]) != 1 {
+		p.fail(i, name, fmt.Errorf("expected a single character"))
+		return 0
+	}
+	return p.fields[i][0]
+}
+
+func (p *statParser) int(i int, name string, bitSize int) int64 {
+	if i >= len(p.fields) {
+		return 0
+	}
+	v, err := strconv.ParseInt(p.fields[i], 10, bitSize)
+	if err != nil {
+		p.fail(i, name, err)
+	}
+	return v
+}
+
+func (p *statParser) uint(i int, name string, bitSize int) uint64 {
+	if i >= len(p.fields) {
+		return 0
+	}
+	v, err := strconv.ParseUint(p.fields[i], 10, bitSize)
+	if err != nil {
+		p.fail(i, name, err)
+	}
+	return v
+}
+
+// tolerate reports whether err, obtained while reading an optional
+// per-process file, can be ignored: the process may have exited since its
+// stat file was read (ENOENT, ESRCH) or the file may be restricted
+// (EACCES, EPERM).
+// The corresponding field is then left empty.
+func tolerate(err error) bool {
+	return errors.Is(err, os.ErrNotExist) ||
+		errors.Is(err, os.ErrPermission) ||
+		errors.Is(err, errors.ErrUnsupported) ||
+		errors.Is(err, syscall.ESRCH)
+}
+
 // Tree is a tree of processes.
+//
+// The slices and maps held by the processes of a tree, like
+// Process.Children, are shared with every copy of the Process values:
+// they should be treated as read-only.
+// Use Process.Copy to obtain a process that can be safely modified.
 type Tree struct {
 	Procs map[int]Process `json:"procs"`
+
+	// BootID identifies the boot of the system the tree was scanned on,
+	// from /proc/sys/kernel/random/boot_id.
+	// It is empty if unknown.
+	// PIDs of trees with different boot IDs are unrelated.
+	BootID string `json:"boot_id,omitempty"`
+
+	// Cycles lists the cycles of parent processes found when creating the
+	// tree, each one from its lowest PID (see WithCyclePolicy).
+	Cycles [][]int `json:"cycles,omitempty"`
 }
 
 // Process stores information about a UNIX process.
@@ -196,4 +692,100 @@ type Process struct {
 	Name     string      `json:"name"`
 	Stat     ProcessStat `json:"stat"`
 	Children []int       `json:"children"`
+
+	// Threads holds the stat of each thread of the process, sorted by
+	// thread ID, when scanned (see WithThreads).
+	Threads []ProcessStat `json:"threads,omitempty"`
+
+	// NS is the inode number of the PID namespace of the process, or zero
+	// if it could not be determined.
+	NS uint64 `json:"ns,omitempty"`
+	// NSPid is the PID of the process within its own PID namespace, the
+	// last of ProcessStat.NSpid, or zero if it could not be determined.
+	NSPid int `json:"nspid,omitempty"`
+
+	// Labels holds arbitrary metadata attached by consumers of the tree
+	// (tags, health status, ...).
+	// New leaves it nil.
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// ScannedAt is the time at which the stat of the process was read.
+	// Scanning a whole tree is not instantaneous, so it differs slightly
+	// from one process to another.
+	ScannedAt time.Time `json:"scanned_at"`
+}
+
+// Copy returns a deep copy of the process, not sharing any slice, map or
+// pointer, like its Children, Threads, Labels or Stat.Namespaces, with p.
+func (p Process) Copy() Process {
+	o := p
+	o.Stat = p.Stat.copy()
+	if p.Children != nil {
+		o.Children = append([]int(nil), p.Children...)
+	}
+	if p.Threads != nil {
+		o.Threads = make([]ProcessStat, len(p.Threads))
+		for i, th := range p.Threads {
+			o.Threads[i] = th.copy()
+		}
+	}
+	if p.Labels != nil {
+		o.Labels = make(map[string]string, len(p.Labels))
+		for k, v := range p.Labels {
+			o.Labels[k] = v
+		}
+	}
+	return o
+}
+
+// copy returns a deep copy of the stat, not sharing any slice, map or
+// pointer with p.
+func (p ProcessStat) copy() ProcessStat {
+	o := p
+	if p.EnvironRaw != nil {
+		o.EnvironRaw = append([]byte(nil), p.EnvironRaw...)
+	}
+	if p.CmdlineRaw != nil {
+		o.CmdlineRaw = append([]byte(nil), p.CmdlineRaw...)
+	}
+	if p.NSpid != nil {
+		o.NSpid = append([]int(nil), p.NSpid...)
+	}
+	if p.SchedStat != nil {
+		v := *p.SchedStat
+		o.SchedStat = &v
+	}
+	if p.FDLimit != nil {
+		v := *p.FDLimit
+		o.FDLimit = &v
+	}
+	if p.Cgroups != nil {
+		o.Cgroups = make([]Cgroup, len(p.Cgroups))
+		for i, cg := range p.Cgroups {
+			if cg.Controllers != nil {
+				cg.Controllers = append([]string(nil), cg.Controllers...)
+			}
+			o.Cgroups[i] = cg
+		}
+	}
+	if p.Namespaces != nil {
+		o.Namespaces = make(map[string]uint64, len(p.Namespaces))
+		for k, v := range p.Namespaces {
+			o.Namespaces[k] = v
+		}
+	}
+	if p.Sockets != nil {
+		o.Sockets = append([]uint64(nil), p.Sockets...)
+	}
+	return o
+}
+
+// pids returns the PIDs of all the processes in the tree, sorted.
+func (t *Tree) pids() []int {
+	pids := make([]int, 0, len(t.Procs))
+	for pid := range t.Procs {
+		pids = append(pids, pid)
+	}
+	sort.Ints(pids)
+	return pids
 }
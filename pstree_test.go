@@ -0,0 +1,316 @@
+// Copyright 2015 The pstree Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pstree
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// statLine returns the content of the /proc/[pid]/stat file of a sleeping
+// process.
+func statLine(pid int, comm string, ppid int) string {
+	return fmt.Sprintf(
+		"%d (%s) S %d %d %d 0 -1 4194560 10 0 2 0 3 4 0 0 20 0 1 0 %d 8192 100 18446744073709551615 0 0 0 0 0 0 0 0 0 0 0 0 17 2 0 0 0 0 0\n",
+		pid, comm, ppid, pid, pid, 100+pid,
+	)
+}
+
+func TestParseStat(t *testing.T) {
+	full := statLine(42, "sleep", 1)
+	for _, tc := range []struct {
+		name string
+		data string
+		comm string
+		err  error
+	}{
+		{name: "full", data: full, comm: "sleep"},
+		{name: "unterminated", data: full[:len(full)-1], comm: "sleep"},
+		{name: "parens", data: statLine(42, "a)b (c", 1), comm: "a)b (c"},
+		{name: "empty", data: "", err: errShortStat},
+		{name: "truncated-comm", data: "42 (sle", err: errShortStat},
+		{name: "truncated-fields", data: full[:len(full)/2], err: errShortStat},
+		{name: "truncated-after-comm", data: "42 (sleep) ", err: errShortStat},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			stat, err := ParseStat([]byte(tc.data))
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("invalid error: got=%v, want=%v", err, tc.err)
+			}
+			if tc.err != nil {
+				return
+			}
+			if stat.PID != 42 || stat.Ppid != 1 || stat.Comm != tc.comm || stat.State != 'S' {
+				t.Fatalf("invalid stat: pid=%d ppid=%d comm=%q state=%q", stat.PID, stat.Ppid, stat.Comm, stat.State)
+			}
+			if stat.Starttime != 142 || stat.Processor != 2 {
+				t.Fatalf("invalid stat: starttime=%d processor=%d", stat.Starttime, stat.Processor)
+			}
+		})
+	}
+}
+
+func TestParsePartialStat(t *testing.T) {
+	// full stat content, without the parentheses around the name.
+	partial := func(comm string) string {
+		return strings.Replace(statLine(42, comm, 1), "("+comm+")", comm, 1)
+	}
+	for _, tc := range []struct {
+		name      string
+		data      string
+		comm      string
+		ppid      int
+		starttime int64
+		err       error
+	}{
+		{name: "one-word", data: partial("sleep"), comm: "sleep", ppid: 1, starttime: 142},
+		{name: "multi-word", data: partial("my proc"), comm: "my proc", ppid: 1, starttime: 142},
+		{name: "digits", data: partial("kworker 0 1"), comm: "kworker 0 1", ppid: 1, starttime: 142},
+		{name: "letters", data: partial("a b c"), comm: "a b c", ppid: 1, starttime: 142},
+		{name: "no-comm", data: partial(""), ppid: 1, starttime: 142},
+		{name: "short", data: "42 my proc S 1\n", comm: "my proc", ppid: 1},
+		{name: "invalid-field", data: strings.Replace(partial("my proc"), " 142 ", " x ", 1), comm: "my proc", ppid: 1},
+		{name: "no-state", data: "42 my proc\n", comm: ""},
+		{name: "truncated", data: "42 my proc S 1", err: errShortStat},
+		{name: "truncated-pid", data: "4", err: errShortStat},
+		{name: "empty", data: "\n", err: errShortStat},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			stat, err := ParseStat([]byte(tc.data))
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("invalid error: got=%v, want=%v", err, tc.err)
+			}
+			if tc.err != nil {
+				return
+			}
+			if !stat.Partial {
+				t.Fatalf("stat not flagged as partial")
+			}
+			if stat.PID != 42 || stat.Comm != tc.comm || stat.Ppid != tc.ppid || stat.Starttime != tc.starttime {
+				t.Fatalf("invalid stat: pid=%d comm=%q ppid=%d starttime=%d", stat.PID, stat.Comm, stat.Ppid, stat.Starttime)
+			}
+		})
+	}
+}
+
+func TestNewFromFSPartialStat(t *testing.T) {
+	fsys := fstest.MapFS{
+		"1/stat":  {Data: []byte(statLine(1, "init", 0))},
+		"42/stat": {Data: []byte("42 my proc S 1 x\n")},
+		"42/comm": {Data: []byte("my proc\n")},
+		"43/stat": {Data: []byte("43 a b c S 42\n")},
+		"43/comm": {Data: []byte("a (b) c\n")},
+	}
+	tree, err := NewFromFS(fsys)
+	if err != nil {
+		t.Fatalf("could not create tree: %+v", err)
+	}
+	for _, tc := range []struct {
+		pid  int
+		name string
+		ppid int
+	}{
+		{42, "my proc", 1},
+		{43, "a (b) c", 42},
+	} {
+		p := tree.Procs[tc.pid]
+		if !p.Stat.Partial || p.Name != tc.name || p.Stat.Comm != tc.name || p.Stat.Ppid != tc.ppid {
+			t.Fatalf("pid=%d: invalid process: partial=%v name=%q comm=%q ppid=%d", tc.pid, p.Stat.Partial, p.Name, p.Stat.Comm, p.Stat.Ppid)
+		}
+	}
+}
+
+func TestParseStatWidths(t *testing.T) {
+	const vsize = 5<<30 + 12345 // above 4 GiB
+	line := strings.Replace(statLine(42, "java", 1), " 8192 ", fmt.Sprintf(" %d ", uint64(vsize)), 1)
+	stat, err := ParseStat([]byte(line))
+	if err != nil {
+		t.Fatalf("could not parse stat: %+v", err)
+	}
+	if stat.Vsize != vsize {
+		t.Fatalf("invalid vsize: got=%d, want=%d", stat.Vsize, uint64(vsize))
+	}
+
+	// ppid is a %d field: it can not exceed 32 bits.
+	line = strings.Replace(statLine(42, "java", 1), ") S 1 ", ") S 2147483648 ", 1)
+	_, err = ParseStat([]byte(line))
+	if !errors.Is(err, strconv.ErrRange) {
+		t.Fatalf("invalid error: got=%v, want=%v", err, strconv.ErrRange)
+	}
+	if got, want := err.Error(), `could not parse stat field 4 (ppid) "2147483648": strconv.ParseInt: parsing "2147483648": value out of range`; got != want {
+		t.Fatalf("invalid error:\ngot= %s\nwant=%s", got, want)
+	}
+}
+
+func TestReadStat(t *testing.T) {
+	full := statLine(42, "sleep", 1)
+	for _, tc := range []struct {
+		name string
+		data string
+		ok   bool
+	}{
+		{name: "full", data: full, ok: true},
+		{name: "unterminated", data: full[:len(full)-1], ok: true},
+		{name: "empty", data: ""},
+		{name: "truncated", data: full[:len(full)/2]},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := newConfig(nil)
+			cfg.fsys = fstest.MapFS{"42/stat": {Data: []byte(tc.data)}}
+			stat, ok, err := readStat("42/stat", cfg)
+			if err != nil {
+				t.Fatalf("could not read stat: %+v", err)
+			}
+			if ok != tc.ok {
+				t.Fatalf("invalid ok: got=%v, want=%v", ok, tc.ok)
+			}
+			if ok && stat.PID != 42 {
+				t.Fatalf("invalid pid: got=%d, want=42", stat.PID)
+			}
+		})
+	}
+
+	t.Run("missing", func(t *testing.T) {
+		cfg := newConfig(nil)
+		cfg.fsys = fstest.MapFS{}
+		_, ok, err := readStat("42/stat", cfg)
+		if err != nil || ok {
+			t.Fatalf("invalid result: ok=%v, err=%v", ok, err)
+		}
+	})
+}
+
+// testProc returns a process named name, with the given PID and parent.
+func testProc(pid, ppid int, name string) Process {
+	return Process{
+		Name: name,
+		Stat: ProcessStat{PID: pid, Ppid: ppid, Comm: name, State: 'S'},
+	}
+}
+
+// newTestTree returns the tree of procs, linked to their parents.
+func newTestTree(t *testing.T, procs ...Process) *Tree {
+	t.Helper()
+	m := make(map[int]Process, len(procs))
+	for _, p := range procs {
+		m[p.Stat.PID] = p
+	}
+	tree, err := newTree(m, newConfig(nil))
+	if err != nil {
+		t.Fatalf("could not create tree: %+v", err)
+	}
+	return tree
+}
+
+func TestNewFromFSHidePID(t *testing.T) {
+	// only the processes of the caller are readable: their parents, and
+	// other processes, are hidden (hidepid=2) or unreadable (hidepid=1).
+	fsys := fstest.MapFS{
+		"1":            {Mode: fs.ModeDir},
+		"500":          {Mode: fs.ModeDir},
+		"1000/stat":    {Data: []byte(statLine(1000, "bash", 500))},
+		"1000/cmdline": {Data: []byte("bash\x00")},
+		"1001/stat":    {Data: []byte(statLine(1001, "vim", 1000))},
+		"1002/stat":    {Data: []byte(statLine(1002, "make", 1000))},
+		"1003/stat":    {Data: []byte(statLine(1003, "cc", 1002))},
+	}
+
+	tree, err := NewFromFS(fsys)
+	if err != nil {
+		t.Fatalf("could not create tree: %+v", err)
+	}
+
+	if got, want := tree.pids(), []int{1000, 1001, 1002, 1003}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("invalid pids: got=%v, want=%v", got, want)
+	}
+	root := tree.Procs[1000]
+	if root.Stat.Ppid != 500 {
+		t.Fatalf("invalid ppid of root: got=%d, want=500", root.Stat.Ppid)
+	}
+	if got, want := root.Children, []int{1001, 1002}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("invalid children: got=%v, want=%v", got, want)
+	}
+	if got, want := tree.Procs[1002].Children, []int{1003}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("invalid children: got=%v, want=%v", got, want)
+	}
+}
+
+func TestProcessCopy(t *testing.T) {
+	newProc := func() Process {
+		p := testProc(1, 0, "init")
+		p.Children = []int{2}
+		p.Labels = map[string]string{"k": "v"}
+		p.Stat.EnvironRaw = []byte("HOME=/\x00")
+		p.Stat.CmdlineRaw = []byte("init\x00")
+		p.Stat.NSpid = []int{1}
+		p.Stat.SchedStat = &SchedStat{RunTime: 1}
+		p.Stat.FDLimit = &Limit{Soft: 1024, Hard: 4096}
+		p.Stat.Cgroups = []Cgroup{{ID: 1, Controllers: []string{"cpu"}, Path: "/"}}
+		p.Stat.Namespaces = map[string]uint64{"pid": 1}
+		p.Stat.Sockets = []uint64{1}
+		p.Threads = []ProcessStat{p.Stat}
+		return p
+	}
+
+	orig := newProc()
+	cp := orig.Copy()
+	for _, stat := range []*ProcessStat{&cp.Stat, &cp.Threads[0]} {
+		stat.EnvironRaw[0] = 'X'
+		stat.CmdlineRaw[0] = 'X'
+		stat.NSpid[0] = 42
+		stat.SchedStat.RunTime = 42
+		stat.FDLimit.Soft = 42
+		stat.Cgroups[0].Path = "/x"
+		stat.Cgroups[0].Controllers[0] = "x"
+		stat.Namespaces["pid"] = 42
+		stat.Sockets[0] = 42
+	}
+	cp.Children[0] = 42
+	cp.Labels["k"] = "x"
+
+	if !reflect.DeepEqual(orig, newProc()) {
+		t.Fatalf("modifying a copy modified the original process")
+	}
+}
+
+func TestNewFromFSStartedAt(t *testing.T) {
+	const btime = 1000000000
+	for _, tc := range []struct {
+		name string
+		stat string
+		want string
+	}{
+		{
+			name: "btime",
+			stat: "cpu  1 2 3 4\nbtime 1000000000\nprocesses 42\n",
+			want: time.Unix(btime, 0).Add(ticks(101)).UTC().Format(time.RFC3339),
+		},
+		{name: "no-btime", stat: "cpu  1 2 3 4\n"},
+		{name: "no-stat"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			fsys := fstest.MapFS{
+				"1/stat": {Data: []byte(statLine(1, "init", 0))},
+			}
+			if tc.stat != "" {
+				fsys["stat"] = &fstest.MapFile{Data: []byte(tc.stat)}
+			}
+			tree, err := NewFromFS(fsys)
+			if err != nil {
+				t.Fatalf("could not create tree: %+v", err)
+			}
+			if got := tree.Procs[1].Stat.StartedAt; got != tc.want {
+				t.Fatalf("invalid start time: got=%q, want=%q", got, tc.want)
+			}
+		})
+	}
+}
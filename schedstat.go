@@ -0,0 +1,77 @@
+// Copyright 2015 The pstree Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pstree
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SchedStat holds the scheduler statistics of a process, as reported by
+// /proc/[pid]/schedstat.
+type SchedStat struct {
+	RunTime    uint64 `json:"run_time"`   // time spent on the CPU, in nanoseconds
+	WaitTime   uint64 `json:"wait_time"`  // time spent waiting on a runqueue, in nanoseconds
+	Timeslices uint64 `json:"timeslices"` // number of timeslices run on the CPU
+}
+
+// WithSchedStat configures whether the scheduler statistics of each process
+// are read into ProcessStat.SchedStat.
+// They are only available on kernels built with CONFIG_SCHEDSTATS;
+// ProcessStat.SchedStat is left nil otherwise.
+func WithSchedStat(v bool) Option {
+	return func(cfg *config) {
+		cfg.schedStat = v
+	}
+}
+
+// readSchedStat reads the schedstat file of the process whose procfs
+// directory is dir.
+// It returns nil if the file is not available.
+func readSchedStat(dir string, cfg config) (*SchedStat, error) {
+	fname := path.Join(dir, "schedstat")
+	data, err := cfg.readFile(fname)
+	switch {
+	case err == nil:
+	case tolerate(err):
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("could not read %s: %w", fname, err)
+	}
+
+	fields := strings.Fields(string(data))
+	if len(fields) < 3 {
+		return nil, fmt.Errorf("%s: file format invalid", fname)
+	}
+	var vs [3]uint64
+	for i := range vs {
+		vs[i], err = strconv.ParseUint(fields[i], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid field %d %q: %w", fname, i+1, fields[i], err)
+		}
+	}
+	return &SchedStat{
+		RunTime:    vs[0],
+		WaitTime:   vs[1],
+		Timeslices: vs[2],
+	}, nil
+}
+
+// SubtreeWait returns the total time the process pid and all its
+// descendants spent waiting on a runqueue, a measure of CPU starvation.
+// It is zero unless the tree was created with WithSchedStat.
+func (t *Tree) SubtreeWait(pid int) time.Duration {
+	var wait uint64
+	_ = t.Walk(pid, func(p Process, depth int) error {
+		if p.Stat.SchedStat != nil {
+			wait += p.Stat.SchedStat.WaitTime
+		}
+		return nil
+	})
+	return time.Duration(wait)
+}
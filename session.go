@@ -0,0 +1,84 @@
+// Copyright 2015 The pstree Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pstree
+
+import "sort"
+
+// ForegroundLeader returns the leader of the foreground process group of the
+// controlling terminal of the process pid, e.g. the command currently running
+// in the foreground of a shell.
+// It returns false if pid is unknown, has no controlling terminal, or if the
+// group leader is not part of the tree.
+func (t *Tree) ForegroundLeader(pid int) (Process, bool) {
+	proc, ok := t.Procs[pid]
+	if !ok || proc.Stat.TTY == 0 || proc.Stat.Tpgid <= 0 {
+		return Process{}, false
+	}
+	// the ID of a process group is the PID of its leader.
+	leader, ok := t.Procs[proc.Stat.Tpgid]
+	if !ok || leader.Stat.Pgrp != proc.Stat.Tpgid {
+		return Process{}, false
+	}
+	return leader, true
+}
+
+// OrphanedPgrps returns the IDs of the orphaned process groups of the tree,
+// sorted.
+//
+// Following POSIX, a process group is orphaned when the parent of each of
+// its members is either a member of the group itself, or is not a member of
+// the session of the group.
+// In other words, a group is not orphaned as long as one of its members has
+// a parent in a different process group of the same session, typically a
+// job-control shell.
+// Parents missing from the tree are considered outside of the session.
+// Processes with a zero process group ID, such as kernel threads, are
+// ignored.
+func (t *Tree) OrphanedPgrps() []int {
+	orphaned := make(map[int]bool)
+	for _, proc := range t.Procs {
+		pgrp := proc.Stat.Pgrp
+		if pgrp == 0 {
+			continue
+		}
+		if _, ok := orphaned[pgrp]; !ok {
+			orphaned[pgrp] = true
+		}
+		parent, ok := t.Procs[proc.Stat.Ppid]
+		if !ok {
+			continue
+		}
+		if parent.Stat.Pgrp != pgrp && parent.Stat.Session == proc.Stat.Session {
+			orphaned[pgrp] = false
+		}
+	}
+
+	var pgrps []int
+	for pgrp, ok := range orphaned {
+		if ok {
+			pgrps = append(pgrps, pgrp)
+		}
+	}
+	sort.Ints(pgrps)
+	return pgrps
+}
+
+// SessionMembers returns the processes of the tree belonging to the same
+// session as the process pid, including pid itself, sorted by PID.
+// It returns nil if pid is unknown.
+func (t *Tree) SessionMembers(pid int) []Process {
+	proc, ok := t.Procs[pid]
+	if !ok {
+		return nil
+	}
+	var procs []Process
+	for _, id := range t.pids() {
+		member := t.Procs[id]
+		if member.Stat.Session == proc.Stat.Session {
+			procs = append(procs, member)
+		}
+	}
+	return procs
+}
@@ -0,0 +1,142 @@
+// Copyright 2015 The pstree Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pstree
+
+import (
+	"fmt"
+	"sort"
+)
+
+// SortChildren sorts the Children of every process in the tree according to
+// less.
+// Processes that are equivalent under less are ordered by PID, so the
+// resulting order is deterministic.
+func (t *Tree) SortChildren(less func(a, b Process) bool) {
+	for _, proc := range t.Procs {
+		if len(proc.Children) < 2 {
+			continue
+		}
+		children := proc.Children
+		sort.Slice(children, func(i, j int) bool {
+			a := t.Procs[children[i]]
+			b := t.Procs[children[j]]
+			return lessPID(less, a, b)
+		})
+	}
+}
+
+// lessPID reports whether a sorts before b according to less, breaking
+// ties by PID.
+func lessPID(less func(a, b Process) bool, a, b Process) bool {
+	switch {
+	case less(a, b):
+		return true
+	case less(b, a):
+		return false
+	}
+	return a.Stat.PID < b.Stat.PID
+}
+
+// ByPID orders processes by increasing PID.
+func ByPID(a, b Process) bool { return a.Stat.PID < b.Stat.PID }
+
+// ByName orders processes by name.
+func ByName(a, b Process) bool { return a.Name < b.Name }
+
+// ByCPU orders processes by decreasing cumulative CPU time (utime+stime).
+func ByCPU(a, b Process) bool { return cpuTime(a) > cpuTime(b) }
+
+// ByMem orders processes by decreasing resident set size.
+func ByMem(a, b Process) bool { return a.Stat.RSS > b.Stat.RSS }
+
+// ByStart orders processes by increasing start time.
+func ByStart(a, b Process) bool { return a.Stat.Starttime < b.Stat.Starttime }
+
+// cpuTime returns the cumulative CPU time of a process, in clock ticks.
+func cpuTime(p Process) uint64 { return p.Stat.Utime + p.Stat.Stime }
+
+// MemoryHogs returns the n processes with the largest resident set size,
+// in decreasing order.
+func (t *Tree) MemoryHogs(n int) []Process {
+	return top(t.Sorted(ByMem), n)
+}
+
+// CPUHogs returns the n processes with the largest cumulative CPU time
+// (utime+stime), in decreasing order.
+//
+// Cumulative CPU time is not a rate: a long-lived, mostly idle process may
+// rank higher than a short-lived busy one.
+// Use CPURate with two snapshots to measure current CPU usage.
+func (t *Tree) CPUHogs(n int) []Process {
+	return top(t.Sorted(ByCPU), n)
+}
+
+// LargestCmdline returns the n processes with the largest command lines,
+// as measured by ProcessStat.ArgsLen, in decreasing order.
+// Huge command lines, as built by some Java launchers, may hit the limits
+// of the system or of the tools displaying them.
+func (t *Tree) LargestCmdline(n int) []Process {
+	return top(t.Sorted(func(a, b Process) bool {
+		return a.Stat.ArgsLen() > b.Stat.ArgsLen()
+	}), n)
+}
+
+// HeaviestChild returns the direct child of the process pid consuming the
+// most of the resource by, one of "rss" (resident set size), "cpu"
+// (cumulative CPU time) or "threads" (number of threads).
+// Ties are broken in favor of the lowest PID.
+// HeaviestChild returns false if pid is unknown or has no children, and an
+// error if by is not a known resource.
+func (t *Tree) HeaviestChild(pid int, by string) (Process, bool, error) {
+	var heavier func(a, b Process) bool
+	switch by {
+	case "rss":
+		heavier = ByMem
+	case "cpu":
+		heavier = ByCPU
+	case "threads":
+		heavier = func(a, b Process) bool { return a.Stat.Nthreads > b.Stat.Nthreads }
+	default:
+		return Process{}, false, fmt.Errorf("pstree: unknown resource %q", by)
+	}
+
+	kids := t.ChildProcs(pid)
+	if len(kids) == 0 {
+		return Process{}, false, nil
+	}
+	heaviest := kids[0]
+	for _, kid := range kids[1:] {
+		if lessPID(heavier, kid, heaviest) {
+			heaviest = kid
+		}
+	}
+	return heaviest, true, nil
+}
+
+// Sorted returns all the processes of the tree, sorted according to less.
+// Processes that are equivalent under less, like processes sharing the same
+// start time with ByStart, are ordered by PID: the result is fully
+// deterministic, as needed by writers and golden tests.
+func (t *Tree) Sorted(less func(a, b Process) bool) []Process {
+	procs := make([]Process, 0, len(t.Procs))
+	for _, proc := range t.Procs {
+		procs = append(procs, proc)
+	}
+	sort.Slice(procs, func(i, j int) bool {
+		return lessPID(less, procs[i], procs[j])
+	})
+	return procs
+}
+
+// top returns at most the first n processes of procs.
+func top(procs []Process, n int) []Process {
+	if n <= 0 {
+		return nil
+	}
+	if n < len(procs) {
+		procs = procs[:n]
+	}
+	return procs
+}
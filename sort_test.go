@@ -0,0 +1,40 @@
+// Copyright 2015 The pstree Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pstree
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSortedByStartTies(t *testing.T) {
+	var procs []Process
+	for _, v := range []struct {
+		pid   int
+		start int64
+	}{
+		{pid: 30, start: 10},
+		{pid: 4, start: 20},
+		{pid: 12, start: 10},
+		{pid: 7, start: 10},
+		{pid: 1, start: 5},
+	} {
+		p := testProc(v.pid, 0, "proc")
+		p.Stat.Starttime = v.start
+		procs = append(procs, p)
+	}
+	tree := newTestTree(t, procs...)
+
+	for i := 0; i < 10; i++ {
+		var got []int
+		for _, p := range tree.Sorted(ByStart) {
+			got = append(got, p.Stat.PID)
+		}
+		want := []int{1, 7, 12, 30, 4}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("invalid order: got=%v, want=%v", got, want)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2015 The pstree Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pstree
+
+import "time"
+
+// Process states, as found in ProcessStat.State.
+// see: http://man7.org/linux/man-pages/man5/proc.5.html
+const (
+	StateRunning     byte = 'R' // running or runnable
+	StateSleeping    byte = 'S' // interruptible sleep
+	StateDiskSleep   byte = 'D' // uninterruptible sleep, usually waiting on I/O
+	StateZombie      byte = 'Z' // terminated, not yet reaped by its parent
+	StateStopped     byte = 'T' // stopped by a signal
+	StateTracingStop byte = 't' // stopped by a debugger
+	StateDead        byte = 'X' // dead, should never be seen
+	StateDeadOld     byte = 'x' // dead (Linux 2.6.33 to 3.13)
+	StateWakekill    byte = 'K' // wakekill (Linux 2.6.33 to 3.13)
+	StateWaking      byte = 'W' // waking (Linux 2.6.33 to 3.13), or paging (before Linux 2.6.0)
+	StateParked      byte = 'P' // parked (Linux 3.9 to 3.13, and since Linux 4.14)
+	StateIdle        byte = 'I' // idle kernel thread (since Linux 4.14)
+)
+
+// stateNames maps process states to human-readable names.
+var stateNames = map[byte]string{
+	StateRunning:     "Running",
+	StateSleeping:    "Sleeping",
+	StateDiskSleep:   "DiskSleep",
+	StateZombie:      "Zombie",
+	StateStopped:     "Stopped",
+	StateTracingStop: "TracingStop",
+	StateDead:        "Dead",
+	StateDeadOld:     "Dead",
+	StateWakekill:    "Wakekill",
+	StateWaking:      "Waking",
+	StateParked:      "Parked",
+	StateIdle:        "Idle",
+}
+
+// StateName returns the human-readable name of a process state, as found in
+// ProcessStat.State, e.g. "Running" for StateRunning or "Zombie" for
+// StateZombie.
+// Unknown states are named "Unknown".
+func StateName(state byte) string {
+	name, ok := stateNames[state]
+	if !ok {
+		return "Unknown"
+	}
+	return name
+}
+
+// StateCounts returns the number of processes of the tree in each state,
+// keyed by StateName.
+func (t *Tree) StateCounts() map[string]int {
+	counts := make(map[string]int)
+	for _, proc := range t.Procs {
+		counts[StateName(proc.Stat.State)]++
+	}
+	return counts
+}
+
+// StuckInD returns the processes in uninterruptible sleep (state 'D'),
+// usually waiting on I/O, which have been running for at least minDuration,
+// sorted by PID.
+//
+// procfs does not report when a process entered the 'D' state, so the
+// uptime of the process is used as a rough proxy: a long-running process
+// that just entered that state is reported as well. Intersecting the
+// results of two snapshots taken minDuration apart gives a better estimate.
+// With a minDuration of zero, all processes in the 'D' state are returned.
+func (t *Tree) StuckInD(minDuration time.Duration) []Process {
+	var procs []Process
+	for _, proc := range t.Sorted(ByPID) {
+		if proc.Stat.State != StateDiskSleep {
+			continue
+		}
+		if minDuration > 0 {
+			up, err := proc.Stat.Uptime()
+			if err != nil || up < minDuration {
+				continue
+			}
+		}
+		procs = append(procs, proc)
+	}
+	return procs
+}
@@ -0,0 +1,182 @@
+// Copyright 2015 The pstree Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pstree
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseStatus parses the content of a /proc/[pid]/status file into stat.
+// Fields absent from data are left untouched.
+func parseStatus(stat *ProcessStat, data []byte) error {
+	for _, line := range strings.Split(string(data), "\n") {
+		key, val, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		val = strings.TrimSpace(val)
+		switch key {
+		case "Tgid":
+			v, err := strconv.Atoi(val)
+			if err != nil {
+				return fmt.Errorf("invalid Tgid %q: %w", val, err)
+			}
+			stat.Tgid = v
+		case "TracerPid":
+			v, err := strconv.Atoi(val)
+			if err != nil {
+				return fmt.Errorf("invalid TracerPid %q: %w", val, err)
+			}
+			stat.TracerPid = v
+		case "Uid":
+			err := parseIDs(&stat.UIDs, val)
+			if err != nil {
+				return fmt.Errorf("invalid Uid %q: %w", val, err)
+			}
+		case "Gid":
+			err := parseIDs(&stat.GIDs, val)
+			if err != nil {
+				return fmt.Errorf("invalid Gid %q: %w", val, err)
+			}
+		case "NSpid":
+			fields := strings.Fields(val)
+			stat.NSpid = make([]int, len(fields))
+			for i, f := range fields {
+				v, err := strconv.Atoi(f)
+				if err != nil {
+					return fmt.Errorf("invalid NSpid %q: %w", val, err)
+				}
+				stat.NSpid[i] = v
+			}
+		case "Cpus_allowed_list":
+			stat.CpusAllowed = val
+		case "VmRSS", "RssAnon", "RssFile", "RssShmem":
+			v, err := parseKB(val)
+			if err != nil {
+				return fmt.Errorf("invalid %s %q: %w", key, val, err)
+			}
+			switch key {
+			case "VmRSS":
+				stat.VmRSS = v
+			case "RssAnon":
+				stat.RssAnon = v
+			case "RssFile":
+				stat.RssFile = v
+			case "RssShmem":
+				stat.RssShmem = v
+			}
+		}
+	}
+	return nil
+}
+
+// parseIDs parses the real, effective, saved set and filesystem IDs of a
+// Uid or Gid line.
+// IDs are 32-bit unsigned values, which may not fit in an int on 32-bit
+// platforms, e.g. with the subordinate IDs of user namespaces.
+func parseIDs(ids *[4]uint32, val string) error {
+	fields := strings.Fields(val)
+	if len(fields) != len(ids) {
+		return fmt.Errorf("expected %d fields, got %d", len(ids), len(fields))
+	}
+	for i, f := range fields {
+		v, err := strconv.ParseUint(f, 10, 32)
+		if err != nil {
+			return err
+		}
+		ids[i] = uint32(v)
+	}
+	return nil
+}
+
+// parseKB parses a memory size in kibibytes, like "1234 kB", and returns it
+// in bytes.
+func parseKB(val string) (int64, error) {
+	v, err := strconv.ParseInt(strings.TrimSuffix(val, " kB"), 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return v * 1024, nil
+}
+
+// CPUCount returns the number of CPUs the process is allowed to run on,
+// according to its CpusAllowed list, or zero if it is unknown.
+func (p ProcessStat) CPUCount() int {
+	if p.CpusAllowed == "" {
+		return 0
+	}
+	n := 0
+	for _, rng := range strings.Split(p.CpusAllowed, ",") {
+		lo, hi, ok := strings.Cut(rng, "-")
+		if !ok {
+			hi = lo
+		}
+		beg, err1 := strconv.Atoi(lo)
+		end, err2 := strconv.Atoi(hi)
+		if err1 != nil || err2 != nil || end < beg {
+			return 0
+		}
+		n += end - beg + 1
+	}
+	return n
+}
+
+// IsSetuid reports whether the effective UID of the process (UIDs[1])
+// differs from its real UID (UIDs[0]), as happens when running a set-user-ID
+// binary.
+func (p ProcessStat) IsSetuid() bool {
+	return p.UIDs[1] != p.UIDs[0]
+}
+
+// SetuidProcs returns the processes whose effective UID differs from their
+// real UID, sorted by PID.
+// See ProcessStat.IsSetuid.
+func (t *Tree) SetuidProcs() []Process {
+	var procs []Process
+	for _, pid := range t.pids() {
+		proc := t.Procs[pid]
+		if proc.Stat.IsSetuid() {
+			procs = append(procs, proc)
+		}
+	}
+	return procs
+}
+
+// TracedProcs returns the processes being traced, e.g. by a debugger or
+// strace(1), sorted by PID.
+// The PID of the tracer of each process is in its Stat.TracerPid.
+func (t *Tree) TracedProcs() []Process {
+	var procs []Process
+	for _, pid := range t.pids() {
+		proc := t.Procs[pid]
+		if proc.Stat.TracerPid != 0 {
+			procs = append(procs, proc)
+		}
+	}
+	return procs
+}
+
+// ExeDeleted reports whether the executable of the process was removed, or
+// replaced, since it was started, as happens after an upgrade or with
+// malware unlinking itself.
+func (p ProcessStat) ExeDeleted() bool {
+	return strings.HasSuffix(p.Exe, " (deleted)")
+}
+
+// DeletedExeProcs returns the processes whose executable was removed or
+// replaced since they were started, sorted by PID.
+// See ProcessStat.ExeDeleted.
+func (t *Tree) DeletedExeProcs() []Process {
+	var procs []Process
+	for _, pid := range t.pids() {
+		proc := t.Procs[pid]
+		if proc.Stat.ExeDeleted() {
+			procs = append(procs, proc)
+		}
+	}
+	return procs
+}
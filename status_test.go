@@ -0,0 +1,72 @@
+// Copyright 2015 The pstree Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pstree
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestParseStatus(t *testing.T) {
+	data, err := os.ReadFile("testdata/status")
+	if err != nil {
+		t.Fatalf("could not read status file: %+v", err)
+	}
+
+	stat := ProcessStat{PID: 1234}
+	err = parseStatus(&stat, data)
+	if err != nil {
+		t.Fatalf("could not parse status file: %+v", err)
+	}
+
+	if stat.Tgid != stat.PID {
+		t.Fatalf("invalid tgid: got=%d, want=%d", stat.Tgid, stat.PID)
+	}
+	if got, want := stat.UIDs, [4]uint32{1000, 1000, 1000, 1000}; got != want {
+		t.Fatalf("invalid uids: got=%v, want=%v", got, want)
+	}
+	if got, want := stat.GIDs, [4]uint32{100, 100, 100, 100}; got != want {
+		t.Fatalf("invalid gids: got=%v, want=%v", got, want)
+	}
+	if got, want := stat.NSpid, []int{1234, 7}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("invalid nspid: got=%v, want=%v", got, want)
+	}
+	if got, want := stat.VmRSS, int64(5340*1024); got != want {
+		t.Fatalf("invalid vmrss: got=%d, want=%d", got, want)
+	}
+	if got, want := stat.CPUCount(), 4; got != want {
+		t.Fatalf("invalid cpu count: got=%d, want=%d", got, want)
+	}
+}
+
+func TestParseStatusIDs(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		line string
+		want [4]uint32
+		err  bool
+	}{
+		{name: "root", line: "Uid:\t0\t0\t0\t0", want: [4]uint32{0, 0, 0, 0}},
+		{name: "subuid", line: "Uid:\t2147483648\t2147483648\t2147483648\t2147483648", want: [4]uint32{1 << 31, 1 << 31, 1 << 31, 1 << 31}},
+		{name: "max", line: "Uid:\t0\t0\t0\t4294967295", want: [4]uint32{0, 0, 0, 1<<32 - 1}},
+		{name: "too-large", line: "Uid:\t4294967296\t0\t0\t0", err: true},
+		{name: "negative", line: "Uid:\t-1\t0\t0\t0", err: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var stat ProcessStat
+			err := parseStatus(&stat, []byte(tc.line+"\n"))
+			switch {
+			case tc.err && err == nil:
+				t.Fatalf("expected an error")
+			case !tc.err && err != nil:
+				t.Fatalf("could not parse status: %+v", err)
+			}
+			if !tc.err && stat.UIDs != tc.want {
+				t.Fatalf("invalid uids: got=%v, want=%v", stat.UIDs, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright 2015 The pstree Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pstree
+
+import "sort"
+
+// ChildProcs returns the direct children of the process pid, in the order
+// of its Children.
+func (t *Tree) ChildProcs(pid int) []Process {
+	proc, ok := t.Procs[pid]
+	if !ok || len(proc.Children) == 0 {
+		return nil
+	}
+	procs := make([]Process, 0, len(proc.Children))
+	for _, cid := range proc.Children {
+		child, ok := t.Procs[cid]
+		if !ok {
+			continue
+		}
+		procs = append(procs, child)
+	}
+	return procs
+}
+
+// CountDescendants returns the number of transitive descendants of the
+// process pid, excluding pid itself.
+func (t *Tree) CountDescendants(pid int) int {
+	n := 0
+	_ = t.Walk(pid, func(p Process, depth int) error {
+		if depth > 0 {
+			n++
+		}
+		return nil
+	})
+	return n
+}
+
+// SubtreeRSS returns the total resident set size, in bytes, of the process
+// pid and all its descendants.
+// Memory shared between processes is counted once per process.
+func (t *Tree) SubtreeRSS(pid int) int64 {
+	var rss int64
+	_ = t.Walk(pid, func(p Process, depth int) error {
+		rss += p.Stat.RSSBytes()
+		return nil
+	})
+	return rss
+}
+
+// SubtreeRSSAnon returns the total resident anonymous memory, in bytes, of
+// the process pid and all its descendants: an estimate of the memory freed
+// by killing them.
+// It is zero on kernels older than Linux 4.5, which do not report it.
+func (t *Tree) SubtreeRSSAnon(pid int) int64 {
+	var rss int64
+	_ = t.Walk(pid, func(p Process, depth int) error {
+		rss += p.Stat.RssAnon
+		return nil
+	})
+	return rss
+}
+
+// OldestDescendant returns the transitive descendant of the process pid
+// which started first, e.g. a leaked child process that was never reaped.
+// Ties are broken in favor of the lowest PID.
+// OldestDescendant returns false if pid is unknown or has no children.
+func (t *Tree) OldestDescendant(pid int) (Process, bool) {
+	var (
+		oldest Process
+		found  bool
+	)
+	_ = t.Walk(pid, func(p Process, depth int) error {
+		if depth == 0 {
+			return nil
+		}
+		if !found || lessPID(ByStart, p, oldest) {
+			oldest, found = p, true
+		}
+		return nil
+	})
+	return oldest, found
+}
+
+// Leaves returns the processes without any children, sorted by PID.
+// Together with Path, it allows to enumerate every root-to-leaf chain of
+// processes.
+func (t *Tree) Leaves() []Process {
+	var procs []Process
+	for _, proc := range t.Procs {
+		if len(proc.Children) == 0 {
+			procs = append(procs, proc)
+		}
+	}
+	sort.Slice(procs, func(i, j int) bool {
+		return procs[i].Stat.PID < procs[j].Stat.PID
+	})
+	return procs
+}
+
+// DepthHistogram returns the number of processes at each depth of the tree,
+// roots (processes whose parent is not part of the tree) having a depth of
+// 0.
+// A flat system has most of its processes at depth 1, as direct children of
+// init, while long chains of spawned processes show up as deep entries.
+func (t *Tree) DepthHistogram() map[int]int {
+	hist := make(map[int]int)
+	for _, pid := range t.pids() {
+		if _, ok := t.Procs[t.Procs[pid].Stat.Ppid]; ok {
+			continue
+		}
+		_ = t.Walk(pid, func(p Process, depth int) error {
+			hist[depth]++
+			return nil
+		})
+	}
+	return hist
+}
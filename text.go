@@ -0,0 +1,327 @@
+// Copyright 2015 The pstree Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pstree
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// TextRenderer renders process trees as box-drawn text:
+//
+//	bash (pid 123)
+//	├─ vim (pid 200)
+//	└─ make (pid 201)
+//	   └─ cc (pid 202)
+type TextRenderer struct {
+	// Label returns the text displayed for a process.
+	// By default, processes are displayed as "name (pid N)".
+	Label func(p Process) string
+
+	// Totals annotates each process with the resident memory and number of
+	// processes of its subtree, e.g. "bash (pid 123) [RSS 45M, 12 procs]".
+	Totals bool
+
+	// Collapse displays sibling processes without children and sharing the
+	// same name as a single line, e.g. "nginx: worker (×256)".
+	Collapse bool
+
+	// MaxDepth limits the depth of the displayed tree, the root having a
+	// depth of 0.
+	// Processes at the maximum depth are annotated with the number of
+	// descendants they hide, e.g. "make (pid 201) (+12)".
+	// A zero MaxDepth displays the whole tree.
+	MaxDepth int
+
+	// MarkKThreads displays kernel threads with a "[k] " prefix, and
+	// without the brackets ps(1) puts around their name, if any, e.g.
+	// "[k] kworker/0:1 (pid 42)".
+	MarkKThreads bool
+
+	// Columns displays the PID and resident set size of processes
+	// right-aligned in columns, after the tree, as htop(1) does.
+	// Processes are then displayed by default with their name only.
+	Columns bool
+
+	// ProcDir, if not empty, appends to each process the directory holding
+	// its information under ProcDir, e.g. "bash (pid 123) /proc/123".
+	// It should match the directory the tree was scanned from (see
+	// WithProcDir).
+	ProcDir string
+
+	// State displays the state of each process, as a single character,
+	// before the tree, as ps(1) f does, e.g. "S └─ vim (pid 200)".
+	State bool
+}
+
+// WriteText writes the subtree rooted at root to w as box-drawn text, using
+// the default TextRenderer.
+func (t *Tree) WriteText(w io.Writer, root int) error {
+	var r TextRenderer
+	return r.Render(w, t, root)
+}
+
+// Render writes the subtree rooted at root to w.
+func (r *TextRenderer) Render(w io.Writer, t *Tree, root int) error {
+	proc, ok := t.Procs[root]
+	if !ok {
+		return fmt.Errorf("pstree: unknown pid=%d", root)
+	}
+
+	var tot map[int]subtreeTotals
+	if r.Totals || r.MaxDepth > 0 {
+		tot = totals(t, root)
+	}
+
+	rows := r.rows(t, proc, tot)
+	if r.State {
+		for i, row := range rows {
+			state := byte(' ') // collapsed processes may have different states.
+			if row.proc != nil && row.proc.Stat.State != 0 {
+				state = row.proc.Stat.State
+			}
+			rows[i].text = string(state) + " " + row.text
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	switch {
+	case r.Columns:
+		writeColumns(bw, rows)
+	default:
+		for _, row := range rows {
+			fmt.Fprintf(bw, "%s\n", row.text)
+		}
+	}
+
+	err := bw.Flush()
+	if err != nil {
+		return fmt.Errorf("pstree: could not write tree: %w", err)
+	}
+	return nil
+}
+
+// textRow is a line of a rendered tree.
+type textRow struct {
+	text string   // branches and label
+	proc *Process // displayed process, nil for collapsed processes
+}
+
+// rows returns the rows of the subtree rooted at root, annotated with the
+// totals of their subtree.
+// The tree is traversed without recursion, so arbitrarily deep trees can be
+// rendered.
+func (r *TextRenderer) rows(t *Tree, root Process, tot map[int]subtreeTotals) []textRow {
+	// item is a row to emit, followed by the rows of the children of its
+	// process, if any.
+	type item struct {
+		row    textRow
+		prefix string // prefix of the rows of the children
+		depth  int
+	}
+
+	var (
+		rows  []textRow
+		seen  = map[int]bool{root.Stat.PID: true}
+		stack = []item{{row: textRow{text: r.line(root, 0, tot), proc: &root}}}
+	)
+	for len(stack) > 0 {
+		it := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		rows = append(rows, it.row)
+
+		if it.row.proc == nil || (r.MaxDepth > 0 && it.depth >= r.MaxDepth) {
+			continue
+		}
+
+		var kids []Process
+		for _, kid := range t.ChildProcs(it.row.proc.Stat.PID) {
+			if seen[kid.Stat.PID] {
+				continue
+			}
+			seen[kid.Stat.PID] = true
+			kids = append(kids, kid)
+		}
+
+		counts := make([]int, len(kids))
+		for i := range counts {
+			counts[i] = 1
+		}
+		if r.Collapse {
+			kids, counts = collapse(kids)
+		}
+
+		// push children in reverse order, so they are emitted in order.
+		for i := len(kids) - 1; i >= 0; i-- {
+			kid := kids[i]
+			branch, indent := "├─ ", "│  "
+			if i == len(kids)-1 {
+				branch, indent = "└─ ", "   "
+			}
+			if n := counts[i]; n > 1 {
+				name, mark := r.name(kid)
+				stack = append(stack, item{row: textRow{
+					text: fmt.Sprintf("%s%s%s%s (×%d)", it.prefix, branch, mark, name, n),
+				}})
+				continue
+			}
+			stack = append(stack, item{
+				row: textRow{
+					text: it.prefix + branch + r.line(kid, it.depth+1, tot),
+					proc: &kid,
+				},
+				prefix: it.prefix + indent,
+				depth:  it.depth + 1,
+			})
+		}
+	}
+	return rows
+}
+
+// writeColumns writes the rows of a tree, followed by the right-aligned PID
+// and resident set size of their process.
+func writeColumns(w io.Writer, rows []textRow) {
+	var (
+		width = 0
+		pids  = make([]string, len(rows))
+		rss   = make([]string, len(rows))
+		wpid  = len("PID")
+		wrss  = len("RSS")
+	)
+	for i, row := range rows {
+		width = max(width, utf8.RuneCountInString(row.text))
+		if row.proc == nil {
+			continue
+		}
+		pids[i] = strconv.Itoa(row.proc.Stat.PID)
+		rss[i] = FormatBytes(row.proc.Stat.RSSBytes())
+		wpid = max(wpid, len(pids[i]))
+		wrss = max(wrss, len(rss[i]))
+	}
+
+	fmt.Fprintf(w, "%s  %*s  %*s\n", strings.Repeat(" ", width), wpid, "PID", wrss, "RSS")
+	for i, row := range rows {
+		pad := strings.Repeat(" ", width-utf8.RuneCountInString(row.text))
+		fmt.Fprintf(w, "%s%s  %*s  %*s\n", row.text, pad, wpid, pids[i], wrss, rss[i])
+	}
+}
+
+// collapse groups the processes without children by name.
+// It returns the first process of each group, at the position of the first
+// member of the group, along with the size of the group.
+func collapse(procs []Process) ([]Process, []int) {
+	var (
+		out    = make([]Process, 0, len(procs))
+		counts = make([]int, 0, len(procs))
+		groups = make(map[string]int) // name -> index in out
+	)
+	for _, p := range procs {
+		if len(p.Children) > 0 {
+			out = append(out, p)
+			counts = append(counts, 1)
+			continue
+		}
+		if i, ok := groups[p.Name]; ok {
+			counts[i]++
+			continue
+		}
+		groups[p.Name] = len(out)
+		out = append(out, p)
+		counts = append(counts, 1)
+	}
+	return out, counts
+}
+
+// line returns the text displayed for a process at the given depth.
+func (r *TextRenderer) line(p Process, depth int, tot map[int]subtreeTotals) string {
+	line := r.label(p, tot)
+	if r.MaxDepth > 0 && depth == r.MaxDepth && len(p.Children) > 0 {
+		line += fmt.Sprintf(" (+%d)", tot[p.Stat.PID].procs-1)
+	}
+	return line
+}
+
+func (r *TextRenderer) label(p Process, tot map[int]subtreeTotals) string {
+	var label, mark string
+	p.Name, mark = r.name(p)
+	switch r.Label {
+	case nil:
+		label = fmt.Sprintf("%s (pid %d)", p.Name, p.Stat.PID)
+		if r.Columns {
+			label = p.Name
+		}
+	default:
+		label = r.Label(p)
+	}
+	label = mark + label
+	if r.ProcDir != "" {
+		label += " " + filepath.Join(r.ProcDir, strconv.Itoa(p.Stat.PID))
+	}
+
+	if r.Totals {
+		var (
+			sub   = tot[p.Stat.PID]
+			procs = "procs"
+		)
+		if sub.procs == 1 {
+			procs = "proc"
+		}
+		label += fmt.Sprintf(" [RSS %s, %d %s]", FormatBytes(sub.rss), sub.procs, procs)
+	}
+	return label
+}
+
+// subtreeTotals are the number of processes of a subtree, its root
+// included, and their total resident set size in bytes.
+type subtreeTotals struct {
+	procs int
+	rss   int64
+}
+
+// totals returns the totals of the subtrees of all the processes of the
+// subtree rooted at root, computed in a single walk rather than with a walk
+// per process.
+func totals(t *Tree, root int) map[int]subtreeTotals {
+	var (
+		tot  = make(map[int]subtreeTotals)
+		kids []subtreeTotals // totals of the walked children, by depth
+	)
+	_ = t.WalkPost(root, func(p Process, depth int) error {
+		sub := subtreeTotals{procs: 1, rss: p.Stat.RSSBytes()}
+		if depth+1 < len(kids) {
+			// all the processes walked at depth+1 since the last process
+			// at depth are children of p.
+			sub.procs += kids[depth+1].procs
+			sub.rss += kids[depth+1].rss
+			kids[depth+1] = subtreeTotals{}
+		}
+		for len(kids) <= depth {
+			kids = append(kids, subtreeTotals{})
+		}
+		kids[depth].procs += sub.procs
+		kids[depth].rss += sub.rss
+		tot[p.Stat.PID] = sub
+		return nil
+	})
+	return tot
+}
+
+// name returns the name displayed for a process, along with its kernel
+// thread mark, if any.
+func (r *TextRenderer) name(p Process) (name, mark string) {
+	if !r.MarkKThreads || !p.Stat.IsKernelThread() {
+		return p.Name, ""
+	}
+	name = p.Name
+	if strings.HasPrefix(name, "[") && strings.HasSuffix(name, "]") {
+		name = name[1 : len(name)-1]
+	}
+	return name, "[k] "
+}
@@ -0,0 +1,46 @@
+// Copyright 2015 The pstree Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pstree
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestRenderTotals(t *testing.T) {
+	procs := []Process{
+		testProc(1, 0, "init"),
+		testProc(10, 1, "sshd"),
+		testProc(11, 10, "bash"),
+		testProc(12, 11, "vim"),
+		testProc(13, 10, "bash"),
+		testProc(20, 1, "cron"),
+	}
+	for i := range procs {
+		procs[i].Stat.RSS = int64(i+1) * 100
+	}
+	tree := newTestTree(t, procs...)
+
+	r := TextRenderer{Totals: true}
+	var sb strings.Builder
+	err := r.Render(&sb, tree, 1)
+	if err != nil {
+		t.Fatalf("could not render tree: %+v", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(sb.String(), "\n"), "\n")
+	for i, pid := range []int{1, 10, 11, 12, 13, 20} {
+		n := tree.CountDescendants(pid) + 1
+		procs := "procs"
+		if n == 1 {
+			procs = "proc"
+		}
+		want := fmt.Sprintf("(pid %d) [RSS %s, %d %s]", pid, FormatBytes(tree.SubtreeRSS(pid)), n, procs)
+		if !strings.HasSuffix(lines[i], want) {
+			t.Errorf("invalid line %d: got=%q, want suffix %q", i, lines[i], want)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright 2015 The pstree Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pstree
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+)
+
+// WithThreads configures whether the threads of each process are scanned
+// from /proc/[pid]/task into Process.Threads.
+func WithThreads(v bool) Option {
+	return func(cfg *config) {
+		cfg.threads = v
+	}
+}
+
+// scanThreads returns the stat of each thread of the process whose procfs
+// directory is dir, sorted by thread ID.
+// The audit IDs of threads are not read.
+func scanThreads(dir string, cfg config) ([]ProcessStat, error) {
+	files, err := fs.Glob(cfg.fsys, path.Join(dir, "task", "[0-9]*"))
+	if err != nil {
+		return nil, fmt.Errorf("could not list threads under %s: %w", dir, err)
+	}
+
+	threads := make([]ProcessStat, 0, len(files))
+	for _, task := range files {
+		thread, ok, err := readStat(path.Join(task, "stat"), cfg)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			// thread vanished since Glob.
+			continue
+		}
+		thread.LoginUID, thread.SessionID = AuditUnset, AuditUnset
+		threads = append(threads, thread)
+	}
+	sort.Slice(threads, func(i, j int) bool {
+		return threads[i].PID < threads[j].PID
+	})
+	return threads, nil
+}
+
+// BusiestThread returns the thread of the process with the largest
+// cumulative CPU time (utime+stime).
+// BusiestThread returns false if the threads of the process were not
+// scanned (see WithThreads).
+func (p Process) BusiestThread() (ProcessStat, bool) {
+	if len(p.Threads) == 0 {
+		return ProcessStat{}, false
+	}
+	busiest := p.Threads[0]
+	for _, th := range p.Threads[1:] {
+		if th.Utime+th.Stime > busiest.Utime+busiest.Stime {
+			busiest = th
+		}
+	}
+	return busiest, true
+}
@@ -0,0 +1,141 @@
+// Copyright 2015 The pstree Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pstree
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// now returns the current time.
+// Tests may replace it to pin the current time.
+var now = time.Now
+
+var boot struct {
+	once sync.Once
+	time time.Time
+	err  error
+}
+
+// bootTime returns the time at which the system booted, as reported by the
+// btime line of /proc/stat.
+func bootTime() (time.Time, error) {
+	boot.once.Do(func() {
+		const fname = "/proc/stat"
+		data, err := os.ReadFile(fname)
+		if err != nil {
+			boot.err = fmt.Errorf("pstree: could not read boot time: %w", err)
+			return
+		}
+		boot.time, boot.err = parseBootTime(fname, data)
+	})
+	return boot.time, boot.err
+}
+
+// readBootTime returns the time at which the system of cfg.fsys booted, as
+// reported by the btime line of its stat file, or the zero time if it can
+// not be read.
+func readBootTime(cfg config) time.Time {
+	data, err := cfg.readFile("stat")
+	if err != nil {
+		return time.Time{}
+	}
+	bt, err := parseBootTime("stat", data)
+	if err != nil {
+		return time.Time{}
+	}
+	return bt
+}
+
+// parseBootTime parses the btime line of the content of the stat file
+// fname.
+func parseBootTime(fname string, data []byte) (time.Time, error) {
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		line := sc.Text()
+		if len(line) < 6 || line[:6] != "btime " {
+			continue
+		}
+		v, err := strconv.ParseInt(line[6:], 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("pstree: invalid boot time in %s %q: %w", fname, line, err)
+		}
+		return time.Unix(v, 0), nil
+	}
+	return time.Time{}, fmt.Errorf("pstree: could not find boot time in %s", fname)
+}
+
+// ticks converts a number of clock ticks to a duration.
+func ticks(n int64) time.Duration {
+	hz := clockTicks()
+	return time.Duration(n/hz)*time.Second + time.Duration(n%hz)*time.Second/time.Duration(hz)
+}
+
+// StartTime returns the time at which the process started.
+func (p ProcessStat) StartTime() (time.Time, error) {
+	bt, err := bootTime()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return bt.Add(ticks(p.Starttime)), nil
+}
+
+// Uptime returns for how long the process has been running.
+func (p ProcessStat) Uptime() (time.Duration, error) {
+	start, err := p.StartTime()
+	if err != nil {
+		return 0, err
+	}
+	return now().Sub(start), nil
+}
+
+// AgeBuckets counts the processes of the tree by their age at time now, in
+// the buckets "<1m", "1-10m", "10m-1h" and ">1h".
+// All buckets are present, even when empty.
+// Processes whose start time is unknown are left out.
+//
+// A sudden increase of short-lived processes, from one snapshot to the
+// next, may reveal a spawning loop.
+func (t *Tree) AgeBuckets(now time.Time) map[string]int {
+	buckets := map[string]int{
+		"<1m":    0,
+		"1-10m":  0,
+		"10m-1h": 0,
+		">1h":    0,
+	}
+	for _, proc := range t.Procs {
+		start, err := proc.Stat.StartTime()
+		if err != nil {
+			continue
+		}
+		switch age := now.Sub(start); {
+		case age < time.Minute:
+			buckets["<1m"]++
+		case age < 10*time.Minute:
+			buckets["1-10m"]++
+		case age < time.Hour:
+			buckets["10m-1h"]++
+		default:
+			buckets[">1h"]++
+		}
+	}
+	return buckets
+}
+
+// readBootID returns the boot ID of the system, or an empty string if it
+// can not be read.
+func readBootID(cfg config) string {
+	data, err := cfg.readFile("sys/kernel/random/boot_id")
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
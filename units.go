@@ -0,0 +1,55 @@
+// Copyright 2015 The pstree Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pstree
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// FormatBytes returns a human-readable representation of n bytes using
+// binary (1024-based) multiples, in the style of ls -h: "512", "12K",
+// "345M" or "1.2G".
+// Values smaller than 10 units are displayed with one decimal.
+func FormatBytes(n int64) string {
+	if n < 0 {
+		// uint64(-n) is correct even for math.MinInt64.
+		return "-" + formatBytes(uint64(-n))
+	}
+	return formatBytes(uint64(n))
+}
+
+func formatBytes(n uint64) string {
+	const units = "KMGTPE"
+	if n < 1024 {
+		return strconv.FormatUint(n, 10)
+	}
+
+	var (
+		v = float64(n) / 1024
+		i = 0
+	)
+	for v >= 1024 && i < len(units)-1 {
+		v /= 1024
+		i++
+	}
+
+	switch {
+	case v < 9.95:
+		return fmt.Sprintf("%.1f%c", v, units[i])
+	case v < 1023.5 || i == len(units)-1:
+		return fmt.Sprintf("%.0f%c", v, units[i])
+	default:
+		// would round up to 1024: switch to the next unit.
+		return fmt.Sprintf("1.0%c", units[i+1])
+	}
+}
+
+// RSSBytes returns the resident set size of the process in bytes, using the
+// page size of the current system.
+func (p ProcessStat) RSSBytes() int64 {
+	return p.RSS * int64(os.Getpagesize())
+}
@@ -0,0 +1,95 @@
+// Copyright 2015 The pstree Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pstree
+
+import "sort"
+
+// ReplaceProc updates, or inserts, the process p in the tree, e.g. from
+// process events, without rescanning the whole tree.
+//
+// The Children of p are ignored: they are kept from the replaced process or,
+// for a new process, made of the processes of the tree whose parent is p.
+// p is added to the Children of its parent, if present in the tree, and
+// removed from those of its former parent if its Ppid changed.
+// Children are kept sorted by PID.
+func (t *Tree) ReplaceProc(p Process) {
+	if t.Procs == nil {
+		t.Procs = make(map[int]Process)
+	}
+
+	pid := p.Stat.PID
+	old, exists := t.Procs[pid]
+	switch {
+	case exists:
+		p.Children = old.Children
+		if old.Stat.Ppid != p.Stat.Ppid {
+			t.unlink(old.Stat.Ppid, pid)
+		}
+	default:
+		p.Children = nil
+		for cid, child := range t.Procs {
+			if child.Stat.Ppid == pid {
+				p.Children = append(p.Children, cid)
+			}
+		}
+		sort.Ints(p.Children)
+	}
+	t.Procs[pid] = p
+	t.link(p.Stat.Ppid, pid)
+}
+
+// RemoveProc removes the process pid from the tree, e.g. on its exit event,
+// and from the Children of its parent.
+//
+// The children of pid are kept in the tree, with their Ppid unchanged: they
+// are roots of the tree until they are updated with ReplaceProc, once they
+// are reparented.
+// RemoveProc does nothing if pid is not in the tree.
+func (t *Tree) RemoveProc(pid int) {
+	p, ok := t.Procs[pid]
+	if !ok {
+		return
+	}
+	delete(t.Procs, pid)
+	if p.Stat.Ppid != pid {
+		t.unlink(p.Stat.Ppid, pid)
+	}
+}
+
+// link adds pid to the children of ppid, if present in the tree.
+func (t *Tree) link(ppid, pid int) {
+	parent, ok := t.Procs[ppid]
+	if !ok || ppid == pid {
+		return
+	}
+	i := sort.SearchInts(parent.Children, pid)
+	if i < len(parent.Children) && parent.Children[i] == pid {
+		return
+	}
+	// Children may be shared with copies of parent: do not modify it in
+	// place.
+	children := make([]int, 0, len(parent.Children)+1)
+	children = append(children, parent.Children[:i]...)
+	children = append(children, pid)
+	children = append(children, parent.Children[i:]...)
+	parent.Children = children
+	t.Procs[ppid] = parent
+}
+
+// unlink removes pid from the children of ppid, if present in the tree.
+func (t *Tree) unlink(ppid, pid int) {
+	parent, ok := t.Procs[ppid]
+	if !ok {
+		return
+	}
+	children := make([]int, 0, len(parent.Children))
+	for _, cid := range parent.Children {
+		if cid != pid {
+			children = append(children, cid)
+		}
+	}
+	parent.Children = children
+	t.Procs[ppid] = parent
+}
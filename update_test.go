@@ -0,0 +1,44 @@
+// Copyright 2015 The pstree Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pstree
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRemoveProc(t *testing.T) {
+	tree := newTestTree(t,
+		testProc(1, 0, "init"),
+		testProc(10, 1, "sshd"),
+		testProc(11, 10, "bash"),
+		testProc(12, 11, "vim"),
+		testProc(20, 1, "cron"),
+	)
+
+	tree.RemoveProc(11)
+	if _, ok := tree.Procs[11]; ok {
+		t.Fatalf("pid=11 still in tree")
+	}
+	if got := tree.Procs[10].Children; len(got) != 0 {
+		t.Fatalf("invalid children of pid=10: got=%v, want=[]", got)
+	}
+	if got, want := tree.Procs[12].Stat.Ppid, 11; got != want {
+		t.Fatalf("invalid ppid of orphan: got=%d, want=%d", got, want)
+	}
+
+	// the orphan is reparented to init.
+	orphan := tree.Procs[12]
+	orphan.Stat.Ppid = 1
+	tree.ReplaceProc(orphan)
+	if got, want := tree.Procs[1].Children, []int{10, 12, 20}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("invalid children of pid=1: got=%v, want=%v", got, want)
+	}
+
+	tree.RemoveProc(42) // unknown pid: no-op.
+	if got, want := len(tree.Procs), 4; got != want {
+		t.Fatalf("invalid number of processes: got=%d, want=%d", got, want)
+	}
+}
@@ -0,0 +1,249 @@
+// Copyright 2015 The pstree Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pstree
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// SkipChildren can be returned by the function passed to Walk to skip the
+// children of the process being visited.
+// It is never returned as an error by Walk.
+var SkipChildren = errors.New("pstree: skip children")
+
+// Walk walks the subtree rooted at root in depth-first pre-order, calling fn
+// for each process with its depth relative to root (root has depth 0).
+// Children are visited in the order of Process.Children.
+// Walk stops at, and returns, the first error returned by fn, except for
+// SkipChildren.
+// Processes already visited are skipped, so malformed trees can not make
+// Walk loop forever.
+// Walk does not recurse, so arbitrarily deep trees can be walked.
+func (t *Tree) Walk(root int, fn func(p Process, depth int) error) error {
+	if _, ok := t.Procs[root]; !ok {
+		return fmt.Errorf("pstree: unknown pid=%d", root)
+	}
+
+	type frame struct {
+		pid   int
+		depth int
+	}
+
+	var (
+		seen  = make(map[int]bool)
+		stack = []frame{{pid: root}}
+	)
+	for len(stack) > 0 {
+		f := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		proc, ok := t.Procs[f.pid]
+		if !ok || seen[f.pid] {
+			continue
+		}
+		seen[f.pid] = true
+
+		err := fn(proc, f.depth)
+		switch {
+		case err == SkipChildren:
+			continue
+		case err != nil:
+			return err
+		}
+		// push children in reverse order, so they are visited in order.
+		for i := len(proc.Children) - 1; i >= 0; i-- {
+			stack = append(stack, frame{pid: proc.Children[i], depth: f.depth + 1})
+		}
+	}
+	return nil
+}
+
+// WalkPost walks the subtree rooted at root in depth-first post-order,
+// calling fn for each process after all its descendants, with its depth
+// relative to root (root has depth 0).
+// This is the natural order to tear down a subtree or to aggregate data
+// bottom-up.
+// Children are visited in the order of Process.Children.
+// WalkPost stops at, and returns, the first error returned by fn.
+// Returning SkipChildren has no effect, the children having already been
+// visited.
+// Like Walk, WalkPost is safe against malformed trees and does not recurse.
+func (t *Tree) WalkPost(root int, fn func(p Process, depth int) error) error {
+	if _, ok := t.Procs[root]; !ok {
+		return fmt.Errorf("pstree: unknown pid=%d", root)
+	}
+
+	type frame struct {
+		proc  Process
+		depth int
+		next  int // index of the next child to visit
+	}
+
+	var (
+		seen  = map[int]bool{root: true}
+		stack = []frame{{proc: t.Procs[root]}}
+	)
+	for len(stack) > 0 {
+		f := &stack[len(stack)-1]
+		if f.next < len(f.proc.Children) {
+			cid := f.proc.Children[f.next]
+			f.next++
+			child, ok := t.Procs[cid]
+			if !ok || seen[cid] {
+				continue
+			}
+			seen[cid] = true
+			stack = append(stack, frame{proc: child, depth: f.depth + 1})
+			continue
+		}
+
+		stack = stack[:len(stack)-1]
+		err := fn(f.proc, f.depth)
+		if err != nil && err != SkipChildren {
+			return err
+		}
+	}
+	return nil
+}
+
+// Path returns the PIDs of the chain of processes leading to pid, from the
+// root of its tree down to pid itself.
+// Path returns nil if pid is not part of the tree.
+// Following parents stops at the first cycle, if the tree is malformed.
+func (t *Tree) Path(pid int) []int {
+	var (
+		path []int
+		seen = make(map[int]bool)
+	)
+	for {
+		proc, ok := t.Procs[pid]
+		if !ok || seen[pid] {
+			break
+		}
+		seen[pid] = true
+		path = append(path, pid)
+		pid = proc.Stat.Ppid
+	}
+
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+// Paths returns the PIDs of every chain of processes leading from root down
+// to a leaf of its subtree, ordered by their first differing PID.
+// Paths returns nil if root is not part of the tree.
+//
+// Each path is a separate slice, so the memory used by Paths grows with the
+// number of leaves times the depth of the subtree.
+func (t *Tree) Paths(root int) [][]int {
+	if _, ok := t.Procs[root]; !ok {
+		return nil
+	}
+
+	type frame struct {
+		pid  int
+		kids []int // children left to visit
+	}
+
+	var (
+		paths [][]int
+		path  []int
+		seen  = make(map[int]bool)
+		stack []frame
+	)
+	push := func(pid int) {
+		seen[pid] = true
+		path = append(path, pid)
+		stack = append(stack, frame{pid: pid, kids: t.pathKids(pid, seen)})
+	}
+	pop := func() {
+		f := stack[len(stack)-1]
+		delete(seen, f.pid)
+		path = path[:len(path)-1]
+		stack = stack[:len(stack)-1]
+	}
+
+	push(root)
+	for len(stack) > 0 {
+		f := &stack[len(stack)-1]
+		switch {
+		case f.kids == nil:
+			paths = append(paths, append([]int(nil), path...))
+			pop()
+		case len(f.kids) == 0:
+			pop()
+		default:
+			cid := f.kids[0]
+			f.kids = f.kids[1:]
+			push(cid)
+		}
+	}
+	return paths
+}
+
+// pathKids returns the sorted children of pid not already on the current
+// path, or nil if there are none.
+func (t *Tree) pathKids(pid int, seen map[int]bool) []int {
+	var kids []int
+	for _, cid := range t.Procs[pid].Children {
+		if _, ok := t.Procs[cid]; ok && !seen[cid] {
+			kids = append(kids, cid)
+		}
+	}
+	sort.Ints(kids)
+	return kids
+}
+
+// TopoSort returns the PIDs of all the processes of the tree, each parent
+// before its children, e.g. to replay the creation of processes.
+// Roots, i.e. processes whose parent is not part of the tree, and the
+// children of each process are ordered by PID, each subtree being listed
+// before the next sibling, so the result is deterministic.
+// Parents are identified by Ppid, regardless of Children.
+//
+// TopoSort returns an error, describing one of them, if processes form
+// cycles of parents.
+func (t *Tree) TopoSort() ([]int, error) {
+	var (
+		roots []int
+		kids  = make(map[int][]int)
+	)
+	for _, pid := range t.pids() {
+		ppid := t.Procs[pid].Stat.Ppid
+		if _, ok := t.Procs[ppid]; !ok {
+			roots = append(roots, pid)
+			continue
+		}
+		if ppid != pid {
+			kids[ppid] = append(kids[ppid], pid)
+		}
+	}
+
+	var (
+		pids  = make([]int, 0, len(t.Procs))
+		stack = make([]int, 0, len(roots))
+	)
+	for i := len(roots) - 1; i >= 0; i-- {
+		stack = append(stack, roots[i])
+	}
+	for len(stack) > 0 {
+		pid := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		pids = append(pids, pid)
+		cids := kids[pid]
+		for i := len(cids) - 1; i >= 0; i-- {
+			stack = append(stack, cids[i])
+		}
+	}
+
+	if len(pids) != len(t.Procs) {
+		return nil, cycleError(findCycles(t.Procs))
+	}
+	return pids, nil
+}
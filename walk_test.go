@@ -0,0 +1,111 @@
+// Copyright 2015 The pstree Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pstree
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// newChain returns a tree made of a chain of n processes, with PIDs 1 to n,
+// each process being the parent of the next one.
+func newChain(t *testing.T, n int) *Tree {
+	t.Helper()
+	procs := make([]Process, n)
+	for i := range procs {
+		procs[i] = testProc(i+1, i, "p")
+	}
+	return newTestTree(t, procs...)
+}
+
+func TestDeepChain(t *testing.T) {
+	const n = 100000
+	tree := newChain(t, n)
+
+	t.Run("walk", func(t *testing.T) {
+		var (
+			cnt  = 0
+			last = 0
+		)
+		err := tree.Walk(1, func(p Process, depth int) error {
+			if depth != p.Stat.PID-1 {
+				return fmt.Errorf("invalid depth for pid=%d: %d", p.Stat.PID, depth)
+			}
+			cnt++
+			last = p.Stat.PID
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("could not walk tree: %+v", err)
+		}
+		if cnt != n || last != n {
+			t.Fatalf("invalid walk: visited=%d, last=%d", cnt, last)
+		}
+	})
+
+	t.Run("walk-post", func(t *testing.T) {
+		var order []int
+		err := tree.WalkPost(1, func(p Process, depth int) error {
+			order = append(order, p.Stat.PID)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("could not walk tree: %+v", err)
+		}
+		if len(order) != n || order[0] != n || order[n-1] != 1 {
+			t.Fatalf("invalid post-order walk: visited=%d", len(order))
+		}
+	})
+
+	t.Run("paths", func(t *testing.T) {
+		paths := tree.Paths(1)
+		if len(paths) != 1 || len(paths[0]) != n {
+			t.Fatalf("invalid paths: %d paths", len(paths))
+		}
+		if paths[0][0] != 1 || paths[0][n-1] != n {
+			t.Fatalf("invalid path ends: %d..%d", paths[0][0], paths[0][n-1])
+		}
+	})
+
+	t.Run("render", func(t *testing.T) {
+		// rendered lines are indented by their depth, so the size of the
+		// output of a whole chain grows with its squared length: render the
+		// first levels only, the rest of the chain being summarized.
+		const depth = 5000
+		r := TextRenderer{MaxDepth: depth}
+		var buf bytes.Buffer
+		err := r.Render(&buf, tree, 1)
+		if err != nil {
+			t.Fatalf("could not render tree: %+v", err)
+		}
+		lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+		if len(lines) != depth+1 {
+			t.Fatalf("invalid number of lines: got=%d, want=%d", len(lines), depth+1)
+		}
+		want := fmt.Sprintf("└─ p (pid %d) (+%d)", depth+1, n-depth-1)
+		if got := lines[depth]; !strings.HasSuffix(got, want) {
+			t.Fatalf("invalid last line: got=%q, want suffix %q", got, want)
+		}
+	})
+	t.Run("render-totals", func(t *testing.T) {
+		const depth = 5000
+		r := TextRenderer{MaxDepth: depth, Totals: true}
+		var buf bytes.Buffer
+		err := r.Render(&buf, tree, 1)
+		if err != nil {
+			t.Fatalf("could not render tree: %+v", err)
+		}
+		lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+		if got, want := lines[0], fmt.Sprintf(", %d procs]", n); !strings.HasSuffix(got, want) {
+			t.Fatalf("invalid first line: got=%q, want suffix %q", got, want)
+		}
+		want := fmt.Sprintf(", %d procs] (+%d)", n-depth, n-depth-1)
+		if got := lines[depth]; !strings.HasSuffix(got, want) {
+			t.Fatalf("invalid last line: got=%q, want suffix %q", got, want)
+		}
+	})
+}
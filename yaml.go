@@ -0,0 +1,63 @@
+// Copyright 2015 The pstree Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pstree
+
+import (
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+// WriteYAML writes the subtree rooted at root to w as nested YAML mappings,
+// with the name, PID and children of each process, in that order:
+//
+//	name: bash
+//	pid: 123
+//	children:
+//	  - name: vim
+//	    pid: 200
+//
+// Children are written in the order of Process.Children, and the children
+// key is left out for processes without children.
+func (t *Tree) WriteYAML(w io.Writer, root int) error {
+	type node struct {
+		Name     string  `yaml:"name"`
+		PID      int     `yaml:"pid"`
+		Children []*node `yaml:"children,omitempty"`
+	}
+
+	if _, ok := t.Procs[root]; !ok {
+		return fmt.Errorf("pstree: unknown pid=%d", root)
+	}
+
+	// ancestors of the visited process, from root, as reached by the walk:
+	// children of malformed trees may not be those of their Ppid.
+	var stack []*node
+	err := t.Walk(root, func(p Process, depth int) error {
+		n := &node{Name: p.Name, PID: p.Stat.PID}
+		stack = append(stack[:depth], n)
+		if depth > 0 {
+			parent := stack[depth-1]
+			parent.Children = append(parent.Children, n)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("pstree: could not write YAML: %w", err)
+	}
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	err = enc.Encode(stack[0])
+	if err != nil {
+		return fmt.Errorf("pstree: could not write YAML: %w", err)
+	}
+	err = enc.Close()
+	if err != nil {
+		return fmt.Errorf("pstree: could not write YAML: %w", err)
+	}
+	return nil
+}
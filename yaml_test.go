@@ -0,0 +1,54 @@
+// Copyright 2015 The pstree Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pstree
+
+import (
+	"strings"
+	"testing"
+)
+
+// mismatchedTree returns a hand-built tree whose children do not match
+// their Ppid, as may be found in untrusted snapshots.
+func mismatchedTree() *Tree {
+	procs := []Process{
+		testProc(1, 0, "init"),
+		testProc(10, 1, "sshd"),
+		testProc(11, 1, "bash"), // child of 10.
+		testProc(12, 99, "vim"), // child of 11, 99 is not in the tree.
+	}
+	procs[0].Children = []int{10}
+	procs[1].Children = []int{11}
+	procs[2].Children = []int{12}
+
+	tree := &Tree{Procs: make(map[int]Process, len(procs))}
+	for _, p := range procs {
+		tree.Procs[p.Stat.PID] = p
+	}
+	return tree
+}
+
+func TestWriteYAMLMismatchedPpid(t *testing.T) {
+	var sb strings.Builder
+	err := mismatchedTree().WriteYAML(&sb, 1)
+	if err != nil {
+		t.Fatalf("could not write YAML: %+v", err)
+	}
+
+	want := `name: init
+pid: 1
+children:
+  - name: sshd
+    pid: 10
+    children:
+      - name: bash
+        pid: 11
+        children:
+          - name: vim
+            pid: 12
+`
+	if got := sb.String(); got != want {
+		t.Fatalf("invalid YAML:\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}